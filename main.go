@@ -1,58 +1,822 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/matu6968/s3-client/s3client"
 )
 
+// exitWaitTimeout is the exit status used when -wait-exists gives up, matching timeout(1)
+const exitWaitTimeout = 124
+
 func main() {
-	filePath := flag.String("file", "", "Path to file to upload")
+	var files multiFlag
+	flag.Var(&files, "file", "Path to file to upload, or - to read from stdin (repeatable to upload several files in parallel)")
+	objectKey := flag.String("key", "", "Full object key to upload to, ignoring -directory (required with -file -)")
 	forcePathStyle := flag.Bool("force-path-style", false, "Enable S3 force path style")
 	configPath := flag.String("config", "", "Path to config file")
+	bucket := flag.String("bucket", "", "Bucket to use, overriding S3CLIENT_BUCKET and the config file")
+	region := flag.String("region", "", "Region to use, overriding S3CLIENT_REGION and the config file")
+	endpoint := flag.String("endpoint", "", "S3 endpoint URL, overriding S3CLIENT_ENDPOINT and the config file")
+	initConfig := flag.Bool("init", false, "Write a commented config template to ~/.config/s3-client/s3config.toml (or -config)")
+	checkConfig := flag.Bool("check-config", false, "Load and validate the configuration without contacting S3, then print \"config OK\" or the problems found")
+	awsProfile := flag.String("aws-profile", "", "Named profile from ~/.aws/credentials to use when the config file has no keys")
+	sessionToken := flag.String("session-token", "", "Session token to go with the configured keys when they are temporary (STS) credentials")
+	roleARN := flag.String("role-arn", "", "IAM role to assume for every operation, using the configured credentials")
+	roleSessionName := flag.String("role-session-name", "", "Session name for -role-arn")
+	externalID := flag.String("external-id", "", "External id required by the trust policy of -role-arn")
+	mfaSerial := flag.String("mfa-serial", "", "MFA device for -role-arn; the token code is asked for on stdin")
+	provider := flag.String("provider", "", "Apply endpoint/region defaults for r2, b2, minio or wasabi")
+	target := flag.String("target", "", "Named target from the [targets.<name>] tables of the config file, overriding the top-level keys")
+	profile := flag.String("profile", "", "Named profile from the [profiles.<name>] tables of the config file")
 	directory := flag.String("directory", "", "Directory in bucket")
+	listBuckets := flag.Bool("list-buckets", false, "List the buckets the credentials can access (no bucket needs to be configured)")
+	createBucket := flag.Bool("create-bucket", false, "Create the configured bucket if it doesn't exist yet")
+	autoCreate := flag.Bool("auto-create", false, "Before uploading, create the configured bucket if it doesn't exist yet")
 	listFiles := flag.Bool("list", false, "List files in bucket")
-	deleteFile := flag.String("delete", "", "Delete file from bucket")
+	delimiter := flag.String("delimiter", "/", "With -list, group keys into directories at this delimiter (with -output json only when given explicitly)")
+	recursive := flag.Bool("recursive", false, "With -list, list every key under the prefix instead of grouping by -delimiter")
+	humanSizes := flag.Bool("h", false, "With -list or -stat, print sizes as KiB/MiB/GiB instead of bytes")
+	flag.BoolVar(humanSizes, "human", false, "Alias for -h")
+	noSummary := flag.Bool("no-summary", false, "With -list, leave out the object count and total size footer")
+	countOnly := flag.Bool("count-only", false, "With -list, only print the number of objects")
+	sortBy := flag.String("sort", "", "With -list, order objects by name, size or date (the whole listing is held in memory)")
+	reverse := flag.Bool("reverse", false, "With -list, reverse the order, e.g. -sort size -reverse for the largest first")
+	diskUsage := flag.Bool("du", false, "Print the number of objects and their total size (under -prefix); with -v also per storage class")
+	prefix := flag.String("prefix", "", "Only list keys starting with this prefix")
+	var deleteFiles stringList
+	flag.Var(&deleteFiles, "delete", "Delete file from bucket (repeatable or comma-separated)")
+	public := flag.Bool("public", false, "Upload with the public-read ACL so the returned URL works anonymously")
+	storageClass := flag.String("storage-class", "", "Storage class for uploads, e.g. STANDARD_IA, ONEZONE_IA or GLACIER")
+	cacheControl := flag.String("cache-control", "", "Cache-Control header for uploads, e.g. \"public, max-age=31536000\" (default from the cache_control config key)")
+	contentDisposition := flag.String("content-disposition", "", "Content-Disposition header for uploads, e.g. 'attachment; filename=\"report.pdf\"'")
+	acl := flag.String("acl", "", "Canned ACL for uploads, e.g. private, public-read or bucket-owner-full-control")
+	deleteWait := flag.Duration("delete-wait", s3client.DefaultDeleteWait, "How long -delete waits for the file to disappear")
+	noWait := flag.Bool("no-wait", false, "Don't wait for -delete to be confirmed")
+	listVersions := flag.Bool("list-versions", false, "List every version and delete marker of the objects (under -prefix) in a versioned bucket")
+	versionID := flag.String("version-id", "", "With -delete, permanently delete this version of the object instead of adding a delete marker")
+	deleteAllVersions := flag.String("delete-all-versions", "", "Permanently delete every version and delete marker of this key in a versioned bucket")
+	listUploads := flag.Bool("list-uploads", false, "List incomplete multipart uploads (under -prefix) with their start time and size")
+	abortUploads := flag.Bool("abort-uploads", false, "Abort incomplete multipart uploads under -prefix, deleting their parts")
+	flag.BoolVar(abortUploads, "cleanup-uploads", false, "Alias for -abort-uploads")
+	olderThan := flag.Duration("older-than", 0, "With -abort-uploads, only abort uploads started longer ago than this, e.g. 24h")
+	deletePrefix := flag.String("delete-prefix", "", "Delete every object under this prefix")
+	assumeYes := flag.Bool("yes", false, "Answer yes to every confirmation prompt")
+	sse := flag.String("sse", "", "Server-side encryption for uploads: AES256 or aws:kms")
+	sseKMSKeyID := flag.String("sse-kms-key-id", "", "KMS key id to use with -sse aws:kms (empty = bucket default key)")
+	flag.StringVar(sseKMSKeyID, "sse-kms-key", "", "Alias for -sse-kms-key-id")
+	var meta multiFlag
+	flag.Var(&meta, "meta", "User metadata key=value to attach to the upload (repeatable)")
+	var tags multiFlag
+	flag.Var(&tags, "tag", "Object tag key=value to attach to the upload (repeatable, at most 10)")
+	var tagList stringList
+	flag.Var(&tagList, "tags", "Comma-separated object tags k1=v1,k2=v2 to attach to the upload")
+	getTags := flag.String("get-tags", "", "Print the tags of an object")
+	setTags := flag.String("set-tags", "", "Replace the tags of this key with the k1=v1,k2=v2 list given as the next argument")
+	partSize := flag.Int64("part-size", 0, "Multipart part size in MiB, at least 5 (0 = config or SDK default)")
+	uploadConcurrency := flag.Int("upload-concurrency", 0, "Number of parts of one upload sent in parallel (0 = config or SDK default)")
+	skipExisting := flag.Bool("skip-existing", false, "Don't upload a file when the object already has the same content")
+	verify := flag.Bool("verify", false, "Send a SHA-256 checksum with the upload and check the stored object against it")
 	overwrite := flag.Bool("overwrite", false, "Overwrite existing file")
+	syncDir := flag.String("sync", "", "Local directory to mirror into the bucket (under -directory)")
+	deleteExtra := flag.Bool("delete-extra", false, "With -sync, also delete remote keys missing locally")
+	var includes, excludes multiFlag
+	flag.Var(&includes, "include", "With -sync or -file, only upload paths matching this glob, e.g. '**/*.go' (repeatable)")
+	flag.Var(&excludes, "exclude", "With -sync or -file, skip paths matching this glob, e.g. '**/node_modules' (repeatable, wins over -include)")
+	dryRun := flag.Bool("dry-run", false, "Show what uploads, deletions, syncs, copies, moves, tagging and bucket creation would do without changing the bucket")
+	statKey := flag.String("stat", "", "Show the metadata of an object")
+	catKey := flag.String("cat", "", "Stream an object to stdout")
+	downloadPrefix := flag.String("download-prefix", "", "Download every object under this prefix into the directory given as the next argument (default: current directory)")
+	downloadKey := flag.String("download", "", "Save this key to the local path given as the next argument (default: its base name)")
+	byteRange := flag.String("range", "", "With -cat or -download, only fetch this byte range (e.g. 0-1023, 1024- or -512)")
+	maxBytes := flag.Int64("max-bytes", 0, "With -cat, stop after writing this many bytes (0 = no limit)")
+	copyKey := flag.String("copy", "", "Copy this key to the destination key given as the next argument")
+	moveKey := flag.String("move", "", "Move this key to the destination key given as the next argument")
+	copyAcross := flag.String("copy-across-buckets", "", "Copy this key (or every key under it when it ends in /) to -target-bucket")
+	targetBucket := flag.String("target-bucket", "", "Destination bucket for -copy-across-buckets")
+	targetConfig := flag.String("target-config", "", "Config file for the destination when it needs a different endpoint or credentials")
+	rateLimit := flag.String("rate-limit", "", "Cap the combined upload and download speed, e.g. 5MB/s or 500KiB/s")
+	gzipUpload := flag.Bool("gzip", false, "Compress uploads with gzip and set Content-Encoding: gzip, appending .gz to the key (already compressed files are uploaded as is)")
+	gzipKeepKey := flag.Bool("gzip-keep-key", false, "With -gzip, keep the key unchanged instead of appending .gz")
+	fixEncoding := flag.String("fix-content-encoding", "", "Set Content-Encoding: gzip on gzip-compressed objects under this prefix")
+	concurrency := flag.Int("concurrency", s3client.DefaultConcurrency, "Number of objects to process in parallel")
+	waitExists := flag.String("wait-exists", "", "Wait until this key exists in the bucket")
+	waitTimeout := flag.Duration("wait-timeout", 60*time.Second, "How long -wait-exists waits before giving up")
+	waitMinDelay := flag.Duration("wait-min-delay", 0, "Minimum delay between -wait-exists polls (0 = SDK default)")
+	waitMaxDelay := flag.Duration("wait-max-delay", 0, "Maximum delay between -wait-exists polls (0 = SDK default)")
+	presign := flag.String("presign", "", "Print a presigned download URL for this key")
+	presignPut := flag.String("presign-put", "", "Print a presigned upload URL for this key")
+	contentType := flag.String("content-type", "", "Content-Type for the upload (detected from the file when empty)")
+	listPresigned := flag.Bool("list-presigned", false, "List objects under -prefix with a presigned download URL for each")
+	expiry := flag.Duration("expiry", 15*time.Minute, "Lifetime of presigned URLs (max 168h)")
+	limit := flag.Int("limit", 0, "With -list or -list-presigned, maximum number of objects to return (0 = no limit)")
+	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification (unsafe; prefer -ca-cert)")
+	caCert := flag.String("ca-cert", "", "PEM file with an extra CA certificate to trust, e.g. for a self-signed endpoint")
+	maxRetries := flag.Int("max-retries", -1, "Retry failed requests up to this many times (-1 = config or SDK default)")
+	retryMode := flag.String("retry-mode", "", "Retry strategy: standard or adaptive (empty = config or SDK default)")
+	timeout := flag.Duration("timeout", 0, "Give up on the operation after this long (0 = no limit; default from the timeout config key)")
+	showProgress := flag.Bool("progress", isTerminal(os.Stderr), "Draw a progress bar on stderr for uploads and -cat (default on when stderr is a terminal)")
+	verbose := flag.Bool("v", false, "Log informational messages (configuration warnings, skipped files, encryption applied) to stderr")
+	veryVerbose := flag.Bool("vv", false, "Log debug messages, including resolved configuration and retries, to stderr")
+	output := flag.String("output", "text", "Output format: text or json")
 	flag.Parse()
 
-	ctx := context.TODO()
+	// Ctrl-C or SIGTERM cancels whatever is in progress; a second signal kills the process as usual
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	stopCancelNotice := context.AfterFunc(ctx, func() {
+		stop()
+		fmt.Fprintln(os.Stderr, "Cancelled, cleaning up...")
+	})
+	defer stopCancelNotice()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if *initConfig {
+		path, err := s3client.InitConfig(*configPath, *overwrite)
+		if errors.Is(err, os.ErrExist) &&
+			(*assumeYes || s3client.PromptYesNo(fmt.Sprintf("Config %s already exists. Overwrite?", path))) {
+			path, err = s3client.InitConfig(path, true)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Wrote config template to", path)
+		return
+	}
 
-	client, err := s3client.LoadClient(ctx, *configPath, *forcePathStyle)
+	logLevel := slog.LevelWarn
+	if *veryVerbose {
+		logLevel = slog.LevelDebug
+	} else if *verbose {
+		logLevel = slog.LevelInfo
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
+	// Flags override S3CLIENT_* environment variables, which override the config file
+	flagConfig := s3client.Config{
+		Bucket:            *bucket,
+		Region:            *region,
+		Endpoint:          *endpoint,
+		SessionToken:      *sessionToken,
+		AWSProfile:        *awsProfile,
+		RoleARN:           *roleARN,
+		RoleSessionName:   *roleSessionName,
+		ExternalID:        *externalID,
+		MFASerial:         *mfaSerial,
+		Provider:          *provider,
+		ForcePathStyle:    *forcePathStyle,
+		PartSizeMiB:       *partSize,
+		UploadConcurrency: *uploadConcurrency,
+		RetryMode:         *retryMode,
+		Insecure:          *insecure,
+		CACert:            *caCert,
+		NoBucket:          *listBuckets,
+	}
+	if *maxRetries >= 0 {
+		flagConfig.MaxAttempts = *maxRetries + 1
+	}
+	cfg, err := s3client.ReadConfig(*configPath, *profile, *target)
+	var client *s3client.Client
+	if err == nil {
+		cfg.Merge(flagConfig)
+		client, err = s3client.LoadClient(ctx, cfg, logger)
+	}
+	if *checkConfig {
+		if err != nil {
+			fmt.Println("Config issues:")
+			for _, issue := range strings.Split(err.Error(), "\n") {
+				fmt.Println("  -", issue)
+			}
+			os.Exit(1)
+		}
+		fmt.Println("config OK")
+		return
+	}
 	if err != nil {
 		fmt.Println("Error initializing client:", err)
 		os.Exit(1)
 	}
+	if !isFlagSet("timeout") && client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.Timeout)
+		defer cancel()
+	}
+	client.AssumeYes = *assumeYes
+	if *rateLimit != "" {
+		bytesPerSecond, err := s3client.ParseRate(*rateLimit)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		client.RateLimit = s3client.NewRateLimiter(bytesPerSecond)
+	}
+	client.DryRun = *dryRun
+	if *showProgress {
+		client.Progress = os.Stderr
+	}
+
+	if *listBuckets {
+		buckets, err := client.ListBuckets(ctx)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := s3client.WriteBuckets(os.Stdout, buckets, *output); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *createBucket {
+		if err := client.EnsureBucket(ctx); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if !*dryRun {
+			fmt.Printf("Bucket %s is ready\n", client.Bucket)
+		}
+		return
+	}
+
+	if *diskUsage {
+		usage, err := client.BucketUsage(ctx, *prefix)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := s3client.WriteUsage(os.Stdout, usage, *output, *verbose || *veryVerbose); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *listFiles && *countOnly {
+		count, totalBytes, err := client.BucketStats(ctx, *prefix)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := s3client.WriteCount(os.Stdout, count, totalBytes, *output); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	if *listFiles {
-		if err := client.ListFiles(ctx); err != nil {
+		listOpts := s3client.ListOptions{Prefix: *prefix, Delimiter: *delimiter, Sort: *sortBy, Reverse: *reverse, Limit: *limit}
+		// JSON stays the flat array of every object scripts expect; grouping into "prefixes" and "objects"
+		// is only done when -delimiter is given explicitly
+		if *recursive || (*output == "json" && !isFlagSet("delimiter")) {
+			listOpts.Delimiter = ""
+		}
+		outOpts := s3client.OutputOptions{Format: *output, HumanSizes: *humanSizes, NoSummary: *noSummary}
+		if err := client.ListFiles(ctx, listOpts, outOpts); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *statKey != "" {
+		stat, err := client.StatObject(ctx, *statKey)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := s3client.WriteStat(os.Stdout, stat, *output, *humanSizes); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *getTags != "" {
+		tagMap, err := client.GetTags(ctx, *getTags)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := s3client.WriteTags(os.Stdout, tagMap, *output); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *setTags != "" {
+		if flag.NArg() != 1 {
+			fmt.Println("Error: -set-tags needs the tags as the next argument, e.g. -set-tags key env=prod,team=web")
+			os.Exit(1)
+		}
+		var pairs stringList
+		pairs.Set(flag.Arg(0))
+		tagMap, err := parseKeyValues(pairs)
+		if err != nil {
+			fmt.Println("Error: -set-tags", err)
+			os.Exit(1)
+		}
+		if err := client.SetTags(ctx, *setTags, tagMap); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if !*dryRun {
+			fmt.Printf("Tagged: %s (%d tags)\n", *setTags, len(tagMap))
+		}
+		return
+	}
+
+	if *catKey != "" {
+		res, err := client.GetRange(ctx, *catKey, *byteRange, *maxBytes, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		if *byteRange != "" || res.Truncated {
+			fmt.Fprintf(os.Stderr, "Fetched %d bytes", res.Written)
+			if res.ContentRange != "" {
+				fmt.Fprintf(os.Stderr, " (%s)", res.ContentRange)
+			}
+			if res.Truncated {
+				fmt.Fprintf(os.Stderr, ", stopped at -max-bytes")
+			}
+			fmt.Fprintln(os.Stderr)
+		}
+		return
+	}
+
+	if *downloadPrefix != "" {
+		destDir := "."
+		if flag.NArg() > 0 {
+			destDir = flag.Arg(0)
+		}
+		if err := client.DownloadPrefix(ctx, *downloadPrefix, destDir, *concurrency); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *downloadKey != "" {
+		dest := path.Base(*downloadKey)
+		if flag.NArg() > 0 {
+			dest = flag.Arg(0)
+		}
+		start, end := int64(0), int64(-1)
+		if *byteRange != "" {
+			start, end, err = s3client.ParseByteRange(*byteRange)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+		res, err := client.DownloadRange(ctx, *downloadKey, dest, start, end)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Downloaded: %s -> %s (%d bytes)\n", *downloadKey, dest, res.Written)
+		return
+	}
+
+	if *copyKey != "" || *moveKey != "" {
+		if flag.NArg() != 1 {
+			fmt.Println("Error: -copy and -move need exactly one destination key after the source, e.g. -copy src.txt dst.txt")
+			os.Exit(1)
+		}
+		dst := flag.Arg(0)
+		if *copyKey != "" {
+			err = client.CopyObject(ctx, *copyKey, dst)
+		} else {
+			err = client.MoveObject(ctx, *moveKey, dst)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if *dryRun {
+			return
+		}
+		if *copyKey != "" {
+			fmt.Printf("Copied: %s -> %s\n", *copyKey, dst)
+		} else {
+			fmt.Printf("Moved: %s -> %s\n", *moveKey, dst)
+		}
+		return
+	}
+
+	if *copyAcross != "" {
+		target := client.WithBucket(*targetBucket)
+		if *targetConfig != "" {
+			// The other flags and the S3CLIENT_* environment describe the source, so only the file
+			// and -target-bucket configure the target
+			targetCfg, err := s3client.ReadConfigFile(*targetConfig)
+			if err == nil {
+				targetCfg.Merge(s3client.Config{Bucket: *targetBucket})
+				target, err = s3client.LoadClient(ctx, targetCfg, logger)
+			}
+			if err != nil {
+				fmt.Println("Error initializing target client:", err)
+				os.Exit(1)
+			}
+		} else if *targetBucket == "" {
+			fmt.Println("Error: -copy-across-buckets needs -target-bucket or -target-config")
+			os.Exit(1)
+		}
+
+		res, err := client.CopyToBucket(ctx, *copyAcross, target)
+		if res != nil && *dryRun {
+			fmt.Printf("Would copy %d object(s), %d bytes to '%s'\n", res.Objects, res.Bytes, target.Bucket)
+		} else if res != nil {
+			fmt.Printf("Copied %d object(s), %d bytes to '%s' (%s)\n", res.Objects, res.Bytes, target.Bucket, res.Method)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *fixEncoding != "" {
+		res, err := client.FixGzipEncoding(ctx, *fixEncoding, *concurrency, *dryRun)
+		if res != nil {
+			verb := "Fixed"
+			if *dryRun {
+				verb = "Would fix"
+			}
+			for _, key := range res.Fixed {
+				fmt.Printf("%s: %s\n", verb, key)
+			}
+			fmt.Printf("%s %d of %d object(s), %d skipped\n", verb, len(res.Fixed), res.Checked, res.Skipped)
+		}
+		if err != nil {
 			fmt.Println("Error:", err)
 			os.Exit(1)
 		}
 		return
 	}
 
-	if *deleteFile != "" {
-		if err := client.DeleteFile(ctx, *deleteFile); err != nil {
+	if *waitExists != "" {
+		waited, err := client.WaitForObject(ctx, *waitExists, *waitTimeout, *waitMinDelay, *waitMaxDelay)
+		if err != nil {
 			fmt.Println("Error:", err)
+			if errors.Is(err, s3client.ErrWaitTimeout) {
+				os.Exit(exitWaitTimeout)
+			}
 			os.Exit(1)
 		}
+		fmt.Printf("%s exists (waited %s)\n", *waitExists, waited.Round(time.Millisecond))
 		return
 	}
 
-	if *filePath != "" {
-		url, err := client.UploadFile(ctx, *filePath, *directory, *overwrite)
+	if *presign != "" {
+		url, err := client.PresignGetURL(ctx, *presign, *expiry)
 		if err != nil {
 			fmt.Println("Error:", err)
 			os.Exit(1)
 		}
-		fmt.Println("Uploaded:", url)
+		fmt.Println(url)
+		return
+	}
+
+	if *presignPut != "" {
+		req, err := client.PresignPut(ctx, *presignPut, *expiry, *contentType)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := s3client.WritePresignedRequest(os.Stdout, req, *output); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if *output == "json" {
+			return
+		}
+		for name, value := range req.Headers {
+			fmt.Fprintf(os.Stderr, "Send with header: %s: %s\n", name, value)
+		}
 		return
 	}
 
-	fmt.Println("No action specified. Use -file, -list, or -delete.")
+	if *listPresigned {
+		objects, err := client.ListPresigned(ctx, *prefix, *limit, *expiry)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := s3client.WritePresigned(os.Stdout, objects, *output); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *listVersions {
+		versions, err := client.ListVersions(ctx, *prefix)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := s3client.WriteVersions(os.Stdout, versions, *output); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *listUploads {
+		uploads, err := client.ListMultipartUploads(ctx, *prefix)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := s3client.WriteUploads(os.Stdout, uploads, *output); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *abortUploads {
+		aborted, err := client.AbortUploads(ctx, *prefix, *olderThan)
+		if err != nil {
+			if len(aborted) > 0 {
+				fmt.Printf("Aborted %d incomplete upload(s) before failing\n", len(aborted))
+			}
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if *dryRun {
+			fmt.Printf("Would abort %d incomplete upload(s)\n", len(aborted))
+		} else {
+			fmt.Printf("Aborted %d incomplete upload(s)\n", len(aborted))
+		}
+		return
+	}
+
+	if *deletePrefix != "" {
+		if !*assumeYes && !*dryRun {
+			count, _, err := client.BucketStats(ctx, *deletePrefix)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			if !s3client.PromptYesNo(fmt.Sprintf("Delete %d object(s) under '%s'?", count, *deletePrefix)) {
+				fmt.Println("Delete cancelled by user")
+				os.Exit(1)
+			}
+		}
+		count, err := client.DeletePrefix(ctx, *deletePrefix, *dryRun)
+		if *dryRun {
+			fmt.Printf("Would delete %d object(s) under '%s'\n", count, *deletePrefix)
+		} else {
+			fmt.Printf("Deleted %d object(s) under '%s'\n", count, *deletePrefix)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(deleteFiles) == 1 && deleteFiles[0] == "-" {
+		deleteFiles = nil
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if key := strings.TrimSpace(scanner.Text()); key != "" {
+				deleteFiles = append(deleteFiles, key)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Println("Error reading keys from stdin:", err)
+			os.Exit(1)
+		}
+		if len(deleteFiles) == 0 {
+			fmt.Println("Error: no keys given on stdin")
+			os.Exit(1)
+		}
+	}
+
+	wait := *deleteWait
+	if *noWait {
+		wait = 0
+	}
+	if *deleteAllVersions != "" {
+		count, err := client.DeleteAllVersions(ctx, *deleteAllVersions)
+		if *dryRun {
+			fmt.Printf("Would delete %d version(s) of '%s'\n", count, *deleteAllVersions)
+		} else {
+			fmt.Printf("Deleted %d version(s) of '%s'\n", count, *deleteAllVersions)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *versionID != "" {
+		if len(deleteFiles) != 1 {
+			fmt.Println("Error: -version-id needs exactly one -delete key")
+			os.Exit(1)
+		}
+		if err := client.DeleteVersion(ctx, deleteFiles[0], *versionID); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(deleteFiles) == 1 {
+		if err := client.DeleteFile(ctx, deleteFiles[0], wait); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(deleteFiles) > 1 {
+		if failed, err := client.DeleteFiles(ctx, deleteFiles); err != nil {
+			fmt.Printf("Error: %d of %d file(s) could not be deleted: %v\n", len(failed), len(deleteFiles), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Uploads and syncs share the same upload settings
+	var opts s3client.UploadOptions
+	if *syncDir != "" || len(files) > 0 {
+		metadata, err := parseKeyValues(meta)
+		if err != nil {
+			fmt.Println("Error: -meta", err)
+			os.Exit(1)
+		}
+		tagMap, err := parseKeyValues(append(tags, tagList...))
+		if err != nil {
+			fmt.Println("Error: -tag", err)
+			os.Exit(1)
+		}
+		for _, keys := range s3client.MetadataCollisions(metadata) {
+			fmt.Fprintf(os.Stderr, "Warning: metadata keys %s only differ in case; S3 stores them lowercased so only one will be kept\n", strings.Join(keys, ", "))
+		}
+		opts = s3client.UploadOptions{
+			Key:                *objectKey,
+			Metadata:           metadata,
+			Tags:               tagMap,
+			ContentType:        *contentType,
+			CacheControl:       *cacheControl,
+			ContentDisposition: *contentDisposition,
+			StorageClass:       *storageClass,
+			SSE:                *sse,
+			SSEKMSKeyID:        *sseKMSKeyID,
+			Verify:             *verify,
+			SkipIfSame:         *skipExisting,
+			Gzip:               *gzipUpload,
+			GzipKeepKey:        *gzipKeepKey,
+		}
+		if *public {
+			if *acl != "" && *acl != "public-read" {
+				fmt.Println("Error: -public can't be combined with -acl", *acl)
+				os.Exit(1)
+			}
+			opts.ACL = "public-read"
+		} else {
+			opts.ACL = *acl
+		}
+	}
+
+	filter := s3client.KeyFilter{Include: includes, Exclude: excludes}
+	if err := filter.Validate(); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	if *syncDir != "" {
+		if !*dryRun {
+			report, err := client.Sync(ctx, *syncDir, *directory, *deleteExtra, filter, opts)
+			// An empty report with an error means the sync never started
+			if err == nil || report != (s3client.SyncReport{}) {
+				fmt.Printf("Sync complete: %d uploaded, %d skipped, %d deleted", report.Uploaded, report.Skipped, report.Deleted)
+				if report.Failed > 0 {
+					fmt.Printf(", %d failed", report.Failed)
+				}
+				fmt.Println()
+			}
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			return
+		}
+		plan, err := client.PlanSync(ctx, *syncDir, *directory, *deleteExtra, filter)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err := plan.Write(os.Stdout, *output); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(files) > 0 && (len(includes) > 0 || len(excludes) > 0) {
+		// Files given directly are matched by name, the part of the path that ends up in the key
+		files = slices.DeleteFunc(files, func(f string) bool { return f != "-" && !filter.Match(filepath.Base(f)) })
+		if len(files) == 0 {
+			fmt.Println("Error: -include/-exclude left no files to upload")
+			os.Exit(1)
+		}
+	}
+
+	if len(files) > 0 {
+		if isFlagSet("key") && *objectKey == "" {
+			fmt.Println("Error: -key must not be empty")
+			os.Exit(1)
+		}
+		if *autoCreate && !*dryRun {
+			if err := client.EnsureBucket(ctx); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+		if len(files) > 1 {
+			if slices.Contains(files, "-") {
+				fmt.Println("Error: -file - can't be combined with other files")
+				os.Exit(1)
+			}
+			if *objectKey != "" {
+				fmt.Println("Error: -key can only be used with a single -file")
+				os.Exit(1)
+			}
+			results, err := client.UploadFiles(ctx, files, *directory, *concurrency, *overwrite, opts)
+			failed := 0
+			for _, r := range results {
+				if r.Err != nil {
+					fmt.Printf("Error uploading %s: %v\n", r.Path, r.Err)
+					failed++
+					continue
+				}
+				if !*dryRun {
+					fmt.Println("Uploaded:", r.URL)
+				}
+			}
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			if failed > 0 {
+				fmt.Printf("%d of %d uploads failed\n", failed, len(results))
+				os.Exit(1)
+			}
+			return
+		}
+
+		var url string
+		if files[0] == "-" {
+			if *objectKey == "" {
+				fmt.Println("Error: -key is required when uploading from stdin (-file -)")
+				os.Exit(1)
+			}
+			url, err = client.UploadReader(ctx, os.Stdin, *objectKey, *overwrite, opts)
+		} else {
+			url, err = client.UploadFile(ctx, files[0], *directory, *overwrite, opts)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if !*dryRun {
+			fmt.Println("Uploaded:", url)
+		}
+		return
+	}
+
+	fmt.Println("No action specified. Use -file, -list, -delete, or -sync.")
 }
 
+// isTerminal reports whether f is attached to a terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,24 @@
+package s3client
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// assumeRoleProvider returns credentials for cfg.RoleARN, obtained from STS with the base credentials
+// of awsCfg. They are refreshed automatically when they expire, so long operations keep working.
+func assumeRoleProvider(awsCfg aws.Config, cfg Config) aws.CredentialsProvider {
+	return stscreds.NewAssumeRoleProvider(sts.NewFromConfig(awsCfg), cfg.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		if cfg.RoleSessionName != "" {
+			o.RoleSessionName = cfg.RoleSessionName
+		}
+		if cfg.ExternalID != "" {
+			o.ExternalID = aws.String(cfg.ExternalID)
+		}
+		if cfg.MFASerial != "" {
+			o.SerialNumber = aws.String(cfg.MFASerial)
+			o.TokenProvider = stscreds.StdinTokenProvider
+		}
+	})
+}
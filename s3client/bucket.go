@@ -0,0 +1,134 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// ErrNotSupported is returned (wrapped) when the endpoint doesn't implement an operation, as some
+// S3-compatible services don't
+var ErrNotSupported = errors.New("not supported by endpoint")
+
+// BucketInfo describes a bucket returned by ListBuckets
+type BucketInfo struct {
+	Name         string    `json:"name"`
+	CreationDate time.Time `json:"creationDate"`
+	// Region is only reported by some endpoints
+	Region string `json:"region,omitempty"`
+}
+
+// ListBuckets returns the buckets the credentials can access. It doesn't use the client's Bucket.
+func (c *Client) ListBuckets(ctx context.Context) ([]BucketInfo, error) {
+	buckets := []BucketInfo{}
+	paginator := s3.NewListBucketsPaginator(c.S3, &s3.ListBucketsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			if isNotSupported(err) {
+				return nil, fmt.Errorf("listing buckets is %w: %w", ErrNotSupported, err)
+			}
+			return nil, fmt.Errorf("listing buckets: %w", err)
+		}
+		for _, b := range page.Buckets {
+			buckets = append(buckets, BucketInfo{
+				Name:         aws.ToString(b.Name),
+				CreationDate: aws.ToTime(b.CreationDate),
+				Region:       aws.ToString(b.BucketRegion),
+			})
+		}
+	}
+	return buckets, nil
+}
+
+// CreateBucket creates the bucket name in region, or in the client's region when region is empty.
+// A bucket that already exists and is owned by the caller counts as created. With DryRun set it only
+// prints the bucket it would create.
+func (c *Client) CreateBucket(ctx context.Context, name, region string) error {
+	if region == "" {
+		region = c.region()
+	}
+	input := &s3.CreateBucketInput{Bucket: &name}
+	// us-east-1 is the default location and S3 rejects it as an explicit constraint
+	if region != "" && region != "us-east-1" {
+		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(region),
+		}
+	}
+	if c.DryRun {
+		fmt.Printf("Would create bucket: %s (Region: %s)\n", name, region)
+		return nil
+	}
+	_, err := c.S3.CreateBucket(ctx, input)
+	var owned *types.BucketAlreadyOwnedByYou
+	switch {
+	case err == nil:
+		c.log().Info("created bucket", "bucket", name, "region", region)
+		return nil
+	case errors.As(err, &owned):
+		// Created earlier or concurrently by someone using the same account
+		return nil
+	default:
+		return fmt.Errorf("creating bucket %s: %w", name, err)
+	}
+}
+
+// EnsureBucket creates the client's bucket unless it already exists. It is safe to call repeatedly.
+func (c *Client) EnsureBucket(ctx context.Context) error {
+	_, err := c.S3.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: &c.Bucket})
+	if err == nil {
+		return nil
+	}
+	if !isNotFound(err) && !isNoSuchBucket(err) {
+		return fmt.Errorf("checking bucket %s: %w", c.Bucket, err)
+	}
+	return c.CreateBucket(ctx, c.Bucket, "")
+}
+
+// region returns the region the underlying S3 client signs for, or "" when it isn't known
+func (c *Client) region() string {
+	if c.Region != "" {
+		return c.Region
+	}
+	if s3c, ok := c.S3.(*s3.Client); ok {
+		return s3c.Options().Region
+	}
+	return ""
+}
+
+// isNoSuchBucket reports whether err is S3 saying the bucket doesn't exist
+func isNoSuchBucket(err error) bool {
+	var noSuchBucket *types.NoSuchBucket
+	if errors.As(err, &noSuchBucket) {
+		return true
+	}
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchBucket"
+}
+
+// isNotSupported reports whether err is the endpoint saying it doesn't implement the operation
+func isNotSupported(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NotImplemented", "MethodNotAllowed", "NotSupported":
+			return true
+		}
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.HTTPStatusCode() {
+		case http.StatusNotImplemented, http.StatusMethodNotAllowed:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// byteRangePattern matches the single-range forms of the HTTP Range header that S3 supports
+var byteRangePattern = regexp.MustCompile(`^bytes=(\d+-\d*|-\d+)$`)
+
+// CatResult reports what GetRange actually transferred
+type CatResult struct {
+	Written      int64
+	ContentRange string
+	Truncated    bool
+}
+
+// CatObject streams the whole of key to w
+func (c *Client) CatObject(ctx context.Context, key string, w io.Writer) error {
+	_, err := c.GetRange(ctx, key, "", 0, w)
+	return err
+}
+
+// GetRange streams key to w. byteRange is an optional HTTP range such as "bytes=0-8191" or just "0-8191";
+// when set only that part of the object is fetched. A positive maxBytes caps how much is written
+// regardless of the range.
+func (c *Client) GetRange(ctx context.Context, key, byteRange string, maxBytes int64, w io.Writer) (*CatResult, error) {
+	key = strings.TrimPrefix(key, "/")
+	input := &s3.GetObjectInput{
+		Bucket: &c.Bucket,
+		Key:    &key,
+	}
+	if byteRange != "" {
+		// Accept the bare "start-end" form as well as the HTTP header form
+		if !strings.HasPrefix(byteRange, "bytes=") {
+			byteRange = "bytes=" + byteRange
+		}
+		if !byteRangePattern.MatchString(byteRange) {
+			return nil, fmt.Errorf("invalid range %q (expected e.g. 0-8191, 1024- or -512, optionally prefixed with bytes=)", byteRange)
+		}
+		input.Range = aws.String(byteRange)
+	}
+
+	// The total for the progress bar comes from the object's size; a range only sends part of it,
+	// which GetObject's own ContentLength reports below
+	var total int64
+	if c.Progress != nil && byteRange == "" {
+		head, err := c.S3.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &c.Bucket, Key: &key})
+		if err == nil {
+			total = aws.ToInt64(head.ContentLength)
+		}
+	}
+
+	out, err := c.S3.GetObject(ctx, input)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidRange" {
+			return nil, fmt.Errorf("range %s is beyond the end of %s", byteRange, key)
+		}
+		return nil, fmt.Errorf("getting object: %w", err)
+	}
+	defer out.Body.Close()
+
+	var body io.Reader = out.Body
+	if maxBytes > 0 {
+		body = io.LimitReader(out.Body, maxBytes)
+	}
+	body = c.withRateLimit(ctx, body)
+	var prog *progress
+	if c.Progress != nil {
+		if total == 0 {
+			total = aws.ToInt64(out.ContentLength)
+		}
+		if maxBytes > 0 {
+			total = min(total, maxBytes)
+		}
+		prog = newProgress(c.Progress, key, total)
+		body = withProgress(body, prog)
+	}
+	n, err := io.Copy(w, body)
+	if prog != nil {
+		prog.done()
+	}
+	res := &CatResult{
+		Written:      n,
+		ContentRange: aws.ToString(out.ContentRange),
+		Truncated:    maxBytes > 0 && n == maxBytes && aws.ToInt64(out.ContentLength) > maxBytes,
+	}
+	if err != nil {
+		return res, fmt.Errorf("reading object: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,87 @@
+package s3client
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// compressedExts are file extensions of formats that are already compressed, so gzip would only
+// add overhead
+var compressedExts = map[string]bool{
+	".gz": true, ".tgz": true, ".zip": true, ".bz2": true, ".xz": true, ".zst": true, ".7z": true,
+	".rar": true, ".br": true, ".lz4": true, ".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".webp": true, ".avif": true, ".mp3": true, ".mp4": true, ".mkv": true, ".webm": true, ".ogg": true,
+}
+
+// isCompressed reports whether a file with this name or content type is already compressed.
+// name may be empty when only the content type is known.
+func isCompressed(name, contentType string) bool {
+	if compressedExts[strings.ToLower(filepath.Ext(name))] {
+		return true
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch mediaType = strings.TrimSpace(mediaType); {
+	case mediaType == "image/svg+xml":
+		return false
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		return true
+	}
+	switch mediaType {
+	case "application/gzip", "application/x-gzip", "application/zip", "application/x-bzip2",
+		"application/x-xz", "application/zstd", "application/x-7z-compressed", "application/vnd.rar":
+		return true
+	}
+	return false
+}
+
+// gzipToTemp compresses r into a temporary file and returns it rewound to the start together with
+// its size. Going through a file rather than memory keeps large uploads cheap and leaves the result
+// seekable for multipart uploads. The caller closes and removes the file.
+func gzipToTemp(r io.Reader) (*os.File, int64, error) {
+	tmp, err := os.CreateTemp("", "s3-client-*.gz")
+	if err != nil {
+		return nil, 0, fmt.Errorf("compressing: %w", err)
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+
+	zw := gzip.NewWriter(tmp)
+	if _, err := io.Copy(zw, r); err != nil {
+		cleanup()
+		return nil, 0, fmt.Errorf("compressing: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		cleanup()
+		return nil, 0, fmt.Errorf("compressing: %w", err)
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, fmt.Errorf("compressing: %w", err)
+	}
+	return tmp, size, nil
+}
+
+// gzipStream returns a reader of the gzip-compressed contents of r, compressing on the fly so
+// streams of unknown length never have to be held anywhere. Closing it stops the compression.
+func gzipStream(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, r)
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
@@ -0,0 +1,264 @@
+package s3client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// envPrefix is prepended to config keys to get the environment variables overriding them
+const envPrefix = "S3CLIENT"
+
+// configNames are the config file names ReadConfig looks for, in order of preference
+var configNames = []string{"s3config.toml", "s3config.yaml", "s3config.yml", "s3config.json"}
+
+// Config is a fully resolved client configuration. ReadConfig fills it from the config file and the
+// environment; callers merge their own overrides (e.g. flags) on top with Merge and pass it to LoadClient.
+type Config struct {
+	// Path is the config file the settings were read from; empty when none was found
+	Path string
+	// Profile is the [profiles.<name>] table the settings were read from
+	Profile string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// AWSProfile is the shared AWS config profile used when there are no keys
+	AWSProfile string
+	// RoleARN is an IAM role to assume with the credentials above; the client then acts as that role
+	RoleARN         string
+	RoleSessionName string
+	ExternalID      string
+	// MFASerial is the MFA device required by the role; the token code is read from stdin
+	MFASerial string
+
+	Bucket    string
+	Region    string
+	Endpoint  string
+	ReturnURL string
+	// Provider selects endpoint/region/path-style presets: r2, b2, minio or wasabi
+	Provider string
+	// AccountID is the Cloudflare account id used by the r2 provider
+	AccountID      string
+	ForcePathStyle bool
+
+	// ACL, SSE, SSEKMSKeyID and CacheControl are upload defaults, see UploadOptions
+	ACL          string
+	SSE          string
+	SSEKMSKeyID  string
+	CacheControl string
+
+	PartSizeMiB       int64
+	UploadConcurrency int
+	// MaxAttempts is the most times a request is tried, including the first; 0 keeps the SDK default
+	MaxAttempts int
+	// RetryMode is "standard" or "adaptive"; empty keeps the SDK default
+	RetryMode string
+	Insecure  bool
+	CACert    string
+	// Timeout is how long a single operation may take; 0 means no limit
+	Timeout time.Duration
+
+	// NoBucket lets LoadClient accept an empty Bucket, for operations such as listing buckets that
+	// don't work on one. It is not read from the config file.
+	NoBucket bool
+}
+
+// ReadConfig reads the config file at path, or the first one found in the working directory or next to
+// DefaultConfigPath when path is empty. profile selects a [profiles.<name>] table instead of the top-level
+// keys, and target a [targets.<name>] table whose keys override them. S3CLIENT_<KEY> environment variables
+// (e.g. S3CLIENT_BUCKET) override the file.
+func ReadConfig(path, profile, target string) (Config, error) {
+	return readConfig(path, profile, target, true)
+}
+
+// ReadConfigFile reads only the top-level keys of the config file at path, ignoring S3CLIENT_<KEY>
+// environment variables. It suits a second endpoint such as a copy target, where the environment
+// describes the source.
+func ReadConfigFile(path string) (Config, error) {
+	return readConfig(path, "", "", false)
+}
+
+func readConfig(path, profile, target string, env bool) (Config, error) {
+	if path == "" {
+		path = findConfig()
+	}
+
+	settings := viper.New()
+	if path != "" {
+		settings.SetConfigFile(path)
+		if err := settings.ReadInConfig(); err != nil {
+			return Config{}, fmt.Errorf("reading config %s: %w", path, err)
+		}
+	} else if profile != "" || target != "" {
+		return Config{}, fmt.Errorf("profile or target requested but no config file was found")
+	}
+
+	base := settings
+	if profile != "" {
+		base = settings.Sub("profiles." + profile)
+		if base == nil {
+			return Config{}, fmt.Errorf("profile %q not found in %s", profile, path)
+		}
+	}
+	if target != "" {
+		targetSettings := settings.Sub("targets." + target)
+		if targetSettings == nil {
+			return Config{}, fmt.Errorf("target %q not found in %s", target, path)
+		}
+		// Unlike a profile, a target only overrides the keys it sets
+		merged := viper.New()
+		if err := merged.MergeConfigMap(base.AllSettings()); err != nil {
+			return Config{}, err
+		}
+		if err := merged.MergeConfigMap(targetSettings.AllSettings()); err != nil {
+			return Config{}, err
+		}
+		base = merged
+	}
+	// S3CLIENT_<KEY> environment variables override the file, e.g. S3CLIENT_BUCKET
+	if env {
+		base.SetEnvPrefix(envPrefix)
+		base.AutomaticEnv()
+	}
+
+	cfg := Config{
+		Path:              path,
+		Profile:           profile,
+		AccessKeyID:       base.GetString("aws_access_key_id"),
+		SecretAccessKey:   base.GetString("aws_secret_access_key"),
+		SessionToken:      base.GetString("aws_session_token"),
+		RoleARN:           base.GetString("role_arn"),
+		RoleSessionName:   base.GetString("role_session_name"),
+		ExternalID:        base.GetString("external_id"),
+		MFASerial:         base.GetString("mfa_serial"),
+		Bucket:            base.GetString("bucket"),
+		Region:            base.GetString("region"),
+		Endpoint:          base.GetString("endpoint"),
+		ReturnURL:         base.GetString("returnurl"),
+		Provider:          base.GetString("provider"),
+		AccountID:         base.GetString("account_id"),
+		ACL:               base.GetString("acl"),
+		SSE:               base.GetString("sse"),
+		SSEKMSKeyID:       base.GetString("sse_kms_key_id"),
+		CacheControl:      base.GetString("cache_control"),
+		PartSizeMiB:       base.GetInt64("part_size"),
+		UploadConcurrency: base.GetInt("upload_concurrency"),
+		RetryMode:         base.GetString("retry_mode"),
+		Insecure:          base.GetBool("insecure"),
+		CACert:            base.GetString("ca_cert"),
+	}
+	if base.IsSet("max_retries") {
+		retries := base.GetInt("max_retries")
+		if retries < 0 {
+			return Config{}, fmt.Errorf("max_retries in %s must not be negative", path)
+		}
+		cfg.MaxAttempts = retries + 1
+	}
+	if base.IsSet("timeout") {
+		timeout, err := time.ParseDuration(base.GetString("timeout"))
+		if err != nil || timeout < 0 {
+			return Config{}, fmt.Errorf("timeout %q in %s: want a duration such as \"30s\", or \"0\" for no limit", base.GetString("timeout"), path)
+		}
+		cfg.Timeout = timeout
+	}
+	return cfg, nil
+}
+
+// Merge overrides c with every field that is set in o. Boolean options can only be switched on.
+// Path and Profile describe where c was read from and are left alone.
+func (c *Config) Merge(o Config) {
+	override(&c.AccessKeyID, o.AccessKeyID)
+	override(&c.SecretAccessKey, o.SecretAccessKey)
+	override(&c.SessionToken, o.SessionToken)
+	override(&c.AWSProfile, o.AWSProfile)
+	override(&c.RoleARN, o.RoleARN)
+	override(&c.RoleSessionName, o.RoleSessionName)
+	override(&c.ExternalID, o.ExternalID)
+	override(&c.MFASerial, o.MFASerial)
+	override(&c.Bucket, o.Bucket)
+	override(&c.Region, o.Region)
+	override(&c.Endpoint, o.Endpoint)
+	override(&c.ReturnURL, o.ReturnURL)
+	override(&c.Provider, o.Provider)
+	override(&c.AccountID, o.AccountID)
+	override(&c.ForcePathStyle, o.ForcePathStyle)
+	override(&c.ACL, o.ACL)
+	override(&c.SSE, o.SSE)
+	override(&c.SSEKMSKeyID, o.SSEKMSKeyID)
+	override(&c.CacheControl, o.CacheControl)
+	override(&c.PartSizeMiB, o.PartSizeMiB)
+	override(&c.UploadConcurrency, o.UploadConcurrency)
+	override(&c.MaxAttempts, o.MaxAttempts)
+	override(&c.RetryMode, o.RetryMode)
+	override(&c.Insecure, o.Insecure)
+	override(&c.CACert, o.CACert)
+	override(&c.Timeout, o.Timeout)
+	override(&c.NoBucket, o.NoBucket)
+}
+
+// override sets *dst to v unless v is the zero value
+func override[T comparable](dst *T, v T) {
+	var zero T
+	if v != zero {
+		*dst = v
+	}
+}
+
+// findConfig returns the first config file found in the working directory or the directory of
+// DefaultConfigPath, or "" when there is none. viper picks the format from the extension.
+func findConfig() string {
+	dirs := []string{"."}
+	if defaultPath, err := DefaultConfigPath(); err == nil {
+		dirs = append(dirs, filepath.Dir(defaultPath))
+	}
+	for _, dir := range dirs {
+		for _, name := range configNames {
+			loc := filepath.Join(dir, name)
+			if _, err := os.Stat(loc); err == nil {
+				return loc
+			}
+		}
+	}
+	return ""
+}
+
+// validateConfig checks for settings whose absence would otherwise only show up as a confusing error
+// from the first request, and reports every problem found at once. region is the effective region,
+// which may also come from the AWS environment.
+func validateConfig(configPath, profile string, requireBucket bool, bucket, region, endpoint, accessKey, secretKey string) error {
+	keyName := func(key string) string {
+		if profile != "" {
+			return "profiles." + profile + "." + key
+		}
+		return key
+	}
+	missing := func(key string) error {
+		env := envPrefix + "_" + strings.ToUpper(key)
+		if configPath == "" {
+			return fmt.Errorf("%s is not set: %s is empty and no config file was found (looked for s3config.toml, .yaml, .yml and .json in . and ~/.config/s3-client)", key, env)
+		}
+		return fmt.Errorf("%s is not set in %s or %s", keyName(key), configPath, env)
+	}
+
+	var errs []error
+	if requireBucket && bucket == "" {
+		errs = append(errs, missing("bucket"))
+	}
+	if region == "" && endpoint == "" {
+		errs = append(errs, fmt.Errorf("%w (or set endpoint, or AWS_REGION)", missing("region")))
+	}
+	// Either both keys or neither: with neither the default AWS credential chain is used
+	if accessKey != "" && secretKey == "" {
+		errs = append(errs, missing("aws_secret_access_key"))
+	}
+	if secretKey != "" && accessKey == "" {
+		errs = append(errs, missing("aws_access_key_id"))
+	}
+	return errors.Join(errs...)
+}
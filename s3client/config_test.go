@@ -0,0 +1,106 @@
+package s3client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `bucket = "file-bucket"
+region = "file-region"
+endpoint = "https://file.example.com"
+
+[targets.backup]
+bucket = "target-bucket"
+`
+
+func writeTestConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "s3config.toml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		env    map[string]string
+		flags  Config
+		want   [3]string // bucket, region, endpoint
+	}{
+		{
+			name: "file",
+			want: [3]string{"file-bucket", "file-region", "https://file.example.com"},
+		},
+		{
+			name:   "target over file",
+			target: "backup",
+			want:   [3]string{"target-bucket", "file-region", "https://file.example.com"},
+		},
+		{
+			name: "env over file",
+			env:  map[string]string{"S3CLIENT_BUCKET": "env-bucket", "S3CLIENT_REGION": "env-region"},
+			want: [3]string{"env-bucket", "env-region", "https://file.example.com"},
+		},
+		{
+			name:   "env over target",
+			target: "backup",
+			env:    map[string]string{"S3CLIENT_BUCKET": "env-bucket"},
+			want:   [3]string{"env-bucket", "file-region", "https://file.example.com"},
+		},
+		{
+			name:  "flags over file",
+			flags: Config{Endpoint: "https://flag.example.com"},
+			want:  [3]string{"file-bucket", "file-region", "https://flag.example.com"},
+		},
+		{
+			name:  "flags over env",
+			env:   map[string]string{"S3CLIENT_BUCKET": "env-bucket", "S3CLIENT_REGION": "env-region"},
+			flags: Config{Bucket: "flag-bucket"},
+			want:  [3]string{"flag-bucket", "env-region", "https://file.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"S3CLIENT_BUCKET", "S3CLIENT_REGION", "S3CLIENT_ENDPOINT"} {
+				t.Setenv(key, tt.env[key])
+				if tt.env[key] == "" {
+					os.Unsetenv(key)
+				}
+			}
+
+			cfg, err := ReadConfig(writeTestConfig(t), "", tt.target)
+			if err != nil {
+				t.Fatalf("ReadConfig: %v", err)
+			}
+			cfg.Merge(tt.flags)
+
+			if got := [3]string{cfg.Bucket, cfg.Region, cfg.Endpoint}; got != tt.want {
+				t.Errorf("bucket, region, endpoint = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigMergeKeepsUnsetFields(t *testing.T) {
+	cfg := Config{Bucket: "bucket", ForcePathStyle: true, MaxAttempts: 3}
+	cfg.Merge(Config{})
+	if cfg.Bucket != "bucket" || !cfg.ForcePathStyle || cfg.MaxAttempts != 3 {
+		t.Errorf("Merge with an empty Config changed %+v", cfg)
+	}
+}
+
+func TestReadConfigFileIgnoresEnv(t *testing.T) {
+	t.Setenv("S3CLIENT_BUCKET", "env-bucket")
+	cfg, err := ReadConfigFile(writeTestConfig(t))
+	if err != nil {
+		t.Fatalf("ReadConfigFile: %v", err)
+	}
+	if cfg.Bucket != "file-bucket" {
+		t.Errorf("bucket = %q, want file-bucket", cfg.Bucket)
+	}
+}
@@ -0,0 +1,164 @@
+package s3client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// CopyResult reports how a cross-bucket copy was carried out
+type CopyResult struct {
+	// Method is "server-side" when S3 copied the data itself or "streamed" when it went through this process
+	Method  string
+	Objects int
+	Bytes   int64
+}
+
+// CopyObject copies srcKey to dstKey within the bucket without downloading the data. With DryRun
+// set it only prints the copy.
+func (c *Client) CopyObject(ctx context.Context, srcKey, dstKey string) error {
+	srcKey = strings.TrimPrefix(srcKey, "/")
+	dstKey = strings.TrimPrefix(dstKey, "/")
+	if c.DryRun {
+		fmt.Printf("Would copy: %s -> %s\n", srcKey, dstKey)
+		return nil
+	}
+	_, err := c.S3.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     &c.Bucket,
+		Key:        &dstKey,
+		CopySource: aws.String(copySource(c.Bucket, srcKey)),
+	})
+	if err != nil {
+		return fmt.Errorf("copying %s to %s: %w", srcKey, dstKey, err)
+	}
+	return nil
+}
+
+// MoveObject renames srcKey to dstKey by copying it and then deleting the original. With DryRun set
+// it only prints the move.
+func (c *Client) MoveObject(ctx context.Context, srcKey, dstKey string) error {
+	if c.DryRun {
+		fmt.Printf("Would move: %s -> %s\n", strings.TrimPrefix(srcKey, "/"), strings.TrimPrefix(dstKey, "/"))
+		return nil
+	}
+	if err := c.CopyObject(ctx, srcKey, dstKey); err != nil {
+		return err
+	}
+	srcKey = strings.TrimPrefix(srcKey, "/")
+	if _, err := c.S3.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: &c.Bucket, Key: &srcKey}); err != nil {
+		return fmt.Errorf("deleting %s after copy: %w", srcKey, err)
+	}
+	return nil
+}
+
+// WithBucket returns a copy of c that operates on bucket using the same connection and credentials
+func (c *Client) WithBucket(bucket string) *Client {
+	cp := *c
+	cp.Bucket = bucket
+	return &cp
+}
+
+// CopyToBucket copies source from c's bucket to the same key in target's bucket. A source ending in "/"
+// copies every object under that prefix. When both clients share the same S3 connection the copy is done
+// server-side with CopyObject (limited to 5 GiB per object); otherwise each object is downloaded from c
+// and re-uploaded through target, which works across providers and credentials. With c's DryRun set
+// the objects are only printed and the result counts what would be copied.
+func (c *Client) CopyToBucket(ctx context.Context, source string, target *Client) (*CopyResult, error) {
+	source = strings.TrimPrefix(source, "/")
+
+	var objects []ObjectInfo
+	if strings.HasSuffix(source, "/") {
+		listing, err := c.ListObjects(ctx, ListOptions{Prefix: source})
+		if err != nil {
+			return nil, err
+		}
+		objects = listing.Objects
+	} else {
+		head, err := c.S3.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &c.Bucket, Key: &source})
+		if err != nil {
+			return nil, fmt.Errorf("checking source object: %w", err)
+		}
+		objects = []ObjectInfo{{Key: source, Size: aws.ToInt64(head.ContentLength)}}
+	}
+
+	res := &CopyResult{Method: "streamed"}
+	if target.S3 == c.S3 {
+		res.Method = "server-side"
+	}
+
+	for _, obj := range objects {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if c.DryRun {
+			fmt.Printf("Would copy: %s -> %s/%s (Size: %d)\n", obj.Key, target.Bucket, obj.Key, obj.Size)
+			res.Objects++
+			res.Bytes += obj.Size
+			continue
+		}
+		var err error
+		if res.Method == "server-side" {
+			_, err = c.S3.CopyObject(ctx, &s3.CopyObjectInput{
+				Bucket:     &target.Bucket,
+				Key:        aws.String(obj.Key),
+				CopySource: aws.String(copySource(c.Bucket, obj.Key)),
+			})
+		} else {
+			err = c.streamTo(ctx, obj.Key, target)
+		}
+		if err != nil {
+			return res, fmt.Errorf("copying %s: %w", obj.Key, err)
+		}
+		res.Objects++
+		res.Bytes += obj.Size
+	}
+	return res, nil
+}
+
+// streamTo downloads key from c and uploads it to the same key through target, keeping the object's
+// headers, metadata, storage class and tags like a server-side copy does
+func (c *Client) streamTo(ctx context.Context, key string, target *Client) error {
+	out, err := c.S3.GetObject(ctx, &s3.GetObjectInput{Bucket: &c.Bucket, Key: &key})
+	if err != nil {
+		return err
+	}
+	defer out.Body.Close()
+
+	input := &s3.PutObjectInput{
+		Bucket:                  &target.Bucket,
+		Key:                     &key,
+		Body:                    c.withRateLimit(ctx, out.Body),
+		ContentType:             out.ContentType,
+		ContentEncoding:         out.ContentEncoding,
+		CacheControl:            out.CacheControl,
+		ContentDisposition:      out.ContentDisposition,
+		ContentLanguage:         out.ContentLanguage,
+		Expires:                 out.Expires,
+		WebsiteRedirectLocation: out.WebsiteRedirectLocation,
+		Metadata:                out.Metadata,
+		StorageClass:            out.StorageClass,
+	}
+	if aws.ToInt32(out.TagCount) > 0 {
+		tags, err := c.GetTags(ctx, key)
+		if err != nil {
+			return err
+		}
+		input.Tagging = aws.String(encodeTags(tags))
+	}
+
+	_, err = target.newUploader(false, aws.ToInt64(out.ContentLength)).Upload(ctx, input)
+	return err
+}
+
+// copySource builds the URL-encoded "bucket/key" value CopyObject expects
+func copySource(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return bucket + "/" + strings.Join(segments, "/")
+}
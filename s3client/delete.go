@@ -0,0 +1,119 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxDeleteBatch is the most keys a single DeleteObjects request accepts
+const maxDeleteBatch = 1000
+
+// DeleteFiles deletes keys with the batch DeleteObjects API, splitting them into requests of up to
+// 1000 keys. Keys that fail do not stop the others; they are returned along with an error combining
+// the messages S3 reported for each of them.
+func (c *Client) DeleteFiles(ctx context.Context, keys []string) ([]string, error) {
+	if c.DryRun {
+		for _, key := range keys {
+			if err := c.printWouldDelete(ctx, strings.TrimPrefix(key, "/")); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	var (
+		failed []string
+		errs   []error
+	)
+	for start := 0; start < len(keys); start += maxDeleteBatch {
+		end := min(start+maxDeleteBatch, len(keys))
+		deleted, batchFailed, err := c.deleteBatch(ctx, keys[start:end])
+		for _, key := range deleted {
+			fmt.Printf("Deleted: %s\n", key)
+		}
+		failed = append(failed, batchFailed...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return failed, errors.Join(errs...)
+}
+
+// DeletePrefix deletes every object whose key starts with prefix and returns how many were removed.
+// An empty prefix is refused so a mistake can't wipe the whole bucket. With dryRun (or the client's
+// DryRun) set the keys are only printed and the returned count is how many would be deleted.
+func (c *Client) DeletePrefix(ctx context.Context, prefix string, dryRun bool) (int, error) {
+	dryRun = dryRun || c.DryRun
+	prefix = strings.TrimPrefix(prefix, "/")
+	if prefix == "" {
+		return 0, fmt.Errorf("refusing to delete with an empty prefix")
+	}
+
+	count := 0
+	var errs []error
+	paginator := s3.NewListObjectsV2Paginator(c.S3, &s3.ListObjectsV2Input{
+		Bucket: &c.Bucket,
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return count, fmt.Errorf("listing files: %w", err)
+		}
+		keys := make([]string, 0, len(page.Contents))
+		for _, item := range page.Contents {
+			keys = append(keys, aws.ToString(item.Key))
+			if dryRun {
+				fmt.Printf("Would delete: %s (Size: %d)\n", aws.ToString(item.Key), aws.ToInt64(item.Size))
+			}
+		}
+		if dryRun {
+			count += len(keys)
+			continue
+		}
+		// A listing page never holds more than 1000 keys, so it fits in a single batch
+		deleted, _, err := c.deleteBatch(ctx, keys)
+		count += len(deleted)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return count, errors.Join(errs...)
+}
+
+// deleteBatch deletes up to 1000 keys in one DeleteObjects call and returns the keys that were
+// removed and the ones that were not
+func (c *Client) deleteBatch(ctx context.Context, keys []string) (deleted, failed []string, err error) {
+	if len(keys) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]types.ObjectIdentifier, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, types.ObjectIdentifier{Key: aws.String(strings.TrimPrefix(key, "/"))})
+	}
+
+	out, err := c.S3.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: &c.Bucket,
+		Delete: &types.Delete{Objects: ids},
+	})
+	if err != nil {
+		return nil, keys, fmt.Errorf("deleting objects: %w", err)
+	}
+
+	for _, d := range out.Deleted {
+		deleted = append(deleted, aws.ToString(d.Key))
+	}
+	var errs []error
+	for _, e := range out.Errors {
+		failed = append(failed, aws.ToString(e.Key))
+		errs = append(errs, fmt.Errorf("deleting %s: %s: %s", aws.ToString(e.Key), aws.ToString(e.Code), aws.ToString(e.Message)))
+	}
+	return deleted, failed, errors.Join(errs...)
+}
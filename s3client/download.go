@@ -0,0 +1,210 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ParseByteRange parses "start-end", "start-" or "-suffix" (optionally prefixed with "bytes=") into
+// the start and end arguments of DownloadRange
+func ParseByteRange(s string) (start, end int64, err error) {
+	spec := strings.TrimPrefix(s, "bytes=")
+	if !byteRangePattern.MatchString("bytes=" + spec) {
+		return 0, 0, fmt.Errorf("invalid range %q (expected e.g. 0-1023, 1024- or -512)", s)
+	}
+	first, last, _ := strings.Cut(spec, "-")
+	if first == "" {
+		// Suffix range: the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n == 0 {
+			return 0, 0, fmt.Errorf("invalid range %q", s)
+		}
+		return -n, -1, nil
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+	end = -1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, fmt.Errorf("invalid range %q: end is before start", s)
+		}
+	}
+	return start, end, nil
+}
+
+// formatByteRange turns DownloadRange's start and end into an HTTP Range value, or "" for the whole object
+func formatByteRange(start, end int64) string {
+	switch {
+	case start < 0:
+		return fmt.Sprintf("bytes=%d", start)
+	case end >= 0:
+		return fmt.Sprintf("bytes=%d-%d", start, end)
+	case start > 0:
+		return fmt.Sprintf("bytes=%d-", start)
+	default:
+		return ""
+	}
+}
+
+// DownloadRange saves bytes start through end (inclusive) of key to the file dest. A negative end reads
+// to the end of the object and a negative start fetches the last -start bytes, so 0, -1 downloads the
+// whole object. The data goes to a temporary file next to dest that only replaces dest once the
+// download succeeded, so a failure leaves an existing dest untouched.
+func (c *Client) DownloadRange(ctx context.Context, key, dest string, start, end int64) (*CatResult, error) {
+	if end >= 0 && start > end {
+		return nil, fmt.Errorf("invalid range: start %d is after end %d", start, end)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.part")
+	if err != nil {
+		return nil, fmt.Errorf("creating %s: %w", dest, err)
+	}
+	// CreateTemp makes the file private; give it the mode dest has, or would get from os.Create
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(dest); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	res, err := c.GetRange(ctx, key, formatByteRange(start, end), 0, tmp)
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("writing %s: %w", dest, closeErr)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dest)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return res, nil
+}
+
+// DownloadPrefix downloads every object under prefix into destDir, recreating the key hierarchy as
+// directories (see downloadPath), with up to concurrency downloads at once (0 uses DefaultConcurrency).
+// Directory placeholders (empty keys ending in "/") are skipped, and keys that would resolve outside
+// destDir, such as ones containing "..", are refused. Existing files are only replaced when AssumeYes
+// is set or the user confirms; declined ones are skipped. Progress bars are only shown when downloading
+// one file at a time, since parallel bars would garble each other. A failed object doesn't stop the
+// others; the returned error combines all failures. A summary line is printed at the end.
+func (c *Client) DownloadPrefix(ctx context.Context, prefix, destDir string, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	dl := c
+	if concurrency > 1 && c.Progress != nil {
+		cp := *c
+		cp.Progress = nil
+		dl = &cp
+	}
+	prefix = strings.TrimPrefix(prefix, "/")
+	listing, err := c.ListObjects(ctx, ListOptions{Prefix: prefix})
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu         sync.Mutex
+		errs       []error
+		downloaded int
+		skipped    int
+		totalBytes int64
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+
+	jobs := make(chan ObjectInfo)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for obj := range jobs {
+				dest, err := downloadPath(destDir, prefix, obj.Key)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				if c.DryRun {
+					fmt.Printf("Would download: %s -> %s (Size: %d)\n", obj.Key, dest, obj.Size)
+					continue
+				}
+				if _, err := os.Stat(dest); err == nil && !c.confirm(fmt.Sprintf("File %s already exists. Overwrite?", dest)) {
+					fmt.Printf("Skipped: %s -> %s (already exists)\n", obj.Key, dest)
+					mu.Lock()
+					skipped++
+					mu.Unlock()
+					continue
+				}
+				if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+					fail(fmt.Errorf("creating directory for %s: %w", obj.Key, err))
+					continue
+				}
+				res, err := dl.DownloadRange(ctx, obj.Key, dest, 0, -1)
+				if err != nil {
+					fail(fmt.Errorf("%s: %w", obj.Key, err))
+					continue
+				}
+				fmt.Printf("Downloaded: %s -> %s\n", obj.Key, dest)
+				mu.Lock()
+				downloaded++
+				totalBytes += res.Written
+				mu.Unlock()
+			}
+		}()
+	}
+
+schedule:
+	for _, obj := range listing.Objects {
+		if strings.HasSuffix(obj.Key, "/") && obj.Size == 0 {
+			continue
+		}
+		select {
+		case jobs <- obj:
+		case <-ctx.Done():
+			break schedule
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if !c.DryRun {
+		note := ""
+		if skipped > 0 {
+			note = fmt.Sprintf(", skipped %d existing", skipped)
+		}
+		fmt.Printf("Downloaded %d file(s), %s total to %s%s\n", downloaded, formatBytes(totalBytes), destDir, note)
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// downloadPath returns where DownloadPrefix saves key under destDir: its path below the last "/" of
+// prefix, so "photos/" downloads "photos/a.png" as "a.png" and "photos" as "photos/a.png". It refuses
+// keys that would land outside destDir.
+func downloadPath(destDir, prefix, key string) (string, error) {
+	base := prefix[:strings.LastIndex(prefix, "/")+1]
+	rel := strings.TrimLeft(strings.TrimPrefix(key, base), "/")
+	if rel == "" {
+		rel = path.Base(key)
+	}
+	rel = filepath.FromSlash(rel)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("refusing to download %q: it would be written outside %s", key, destDir)
+	}
+	return filepath.Join(destDir, rel), nil
+}
@@ -0,0 +1,190 @@
+package s3client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// gzipMagic is the two-byte header every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// EncodingFixResult summarizes a FixGzipEncoding run
+type EncodingFixResult struct {
+	// Checked counts every object under the prefix, including those skipped or failed
+	Checked int
+	// Fixed lists the keys that were (or, in dry-run mode, would be) marked as gzip encoded
+	Fixed []string
+	// Skipped counts the objects that didn't need fixing: already encoded, not gzip or too small to tell
+	Skipped int
+}
+
+// FixGzipEncoding finds objects under prefix whose content is gzip compressed but which are served
+// without "Content-Encoding: gzip", and sets the header by copying each object onto itself with its
+// metadata replaced. The copy keeps the object's headers, metadata, storage class and encryption, and
+// its ACL is restored afterwards since a copy would otherwise make it private. Only the first two bytes
+// of each candidate are downloaded. With dryRun (or the client's DryRun) set the objects are detected
+// but left untouched. Up to concurrency objects are processed at once.
+func (c *Client) FixGzipEncoding(ctx context.Context, prefix string, concurrency int, dryRun bool) (*EncodingFixResult, error) {
+	dryRun = dryRun || c.DryRun
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	listing, err := c.ListObjects(ctx, ListOptions{Prefix: prefix})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		res  = &EncodingFixResult{}
+		errs []error
+		wg   sync.WaitGroup
+	)
+	jobs := make(chan ObjectInfo)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for obj := range jobs {
+				fixed, err := c.fixGzipEncoding(ctx, obj, dryRun)
+				mu.Lock()
+				res.Checked++
+				switch {
+				case err != nil:
+					errs = append(errs, fmt.Errorf("%s: %w", obj.Key, err))
+				case fixed:
+					res.Fixed = append(res.Fixed, obj.Key)
+				default:
+					res.Skipped++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+schedule:
+	for _, obj := range listing.Objects {
+		if obj.Size < int64(len(gzipMagic)) {
+			mu.Lock()
+			res.Checked++
+			res.Skipped++
+			mu.Unlock()
+			continue
+		}
+		select {
+		case jobs <- obj:
+		case <-ctx.Done():
+			break schedule
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return res, errors.Join(errs...)
+}
+
+// fixGzipEncoding handles a single object and reports whether it needed fixing
+func (c *Client) fixGzipEncoding(ctx context.Context, obj ObjectInfo, dryRun bool) (bool, error) {
+	head, err := c.S3.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &c.Bucket, Key: aws.String(obj.Key)})
+	if err != nil {
+		return false, fmt.Errorf("reading metadata: %w", err)
+	}
+	if strings.Contains(strings.ToLower(aws.ToString(head.ContentEncoding)), "gzip") {
+		return false, nil
+	}
+
+	out, err := c.S3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &c.Bucket,
+		Key:    aws.String(obj.Key),
+		Range:  aws.String("bytes=0-1"),
+	})
+	if err != nil {
+		return false, fmt.Errorf("reading header bytes: %w", err)
+	}
+	magic := make([]byte, len(gzipMagic))
+	_, err = io.ReadFull(out.Body, magic)
+	out.Body.Close()
+	if err != nil {
+		return false, fmt.Errorf("reading header bytes: %w", err)
+	}
+	if !bytes.Equal(magic, gzipMagic) {
+		return false, nil
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	// A copy resets the ACL to private, so read it first to restore grants such as public-read
+	acl, err := c.S3.GetObjectAcl(ctx, &s3.GetObjectAclInput{Bucket: &c.Bucket, Key: aws.String(obj.Key)})
+	if err != nil && !isNotSupported(err) {
+		return false, fmt.Errorf("reading ACL: %w", err)
+	}
+
+	// REPLACE drops everything not sent with the copy, so carry the existing headers over
+	input := &s3.CopyObjectInput{
+		Bucket:                  &c.Bucket,
+		Key:                     aws.String(obj.Key),
+		CopySource:              aws.String(copySource(c.Bucket, obj.Key)),
+		MetadataDirective:       types.MetadataDirectiveReplace,
+		ContentEncoding:         aws.String("gzip"),
+		ContentType:             head.ContentType,
+		CacheControl:            head.CacheControl,
+		ContentDisposition:      head.ContentDisposition,
+		ContentLanguage:         head.ContentLanguage,
+		Expires:                 head.Expires,
+		WebsiteRedirectLocation: head.WebsiteRedirectLocation,
+		Metadata:                head.Metadata,
+		StorageClass:            head.StorageClass,
+		ServerSideEncryption:    head.ServerSideEncryption,
+	}
+	if head.ServerSideEncryption != types.ServerSideEncryptionAes256 {
+		input.SSEKMSKeyId = head.SSEKMSKeyId
+		input.BucketKeyEnabled = head.BucketKeyEnabled
+	}
+	if _, err := c.S3.CopyObject(ctx, input); err != nil {
+		return false, fmt.Errorf("rewriting headers: %w", err)
+	}
+
+	if acl != nil && hasExtraGrants(acl) {
+		_, err := c.S3.PutObjectAcl(ctx, &s3.PutObjectAclInput{
+			Bucket:              &c.Bucket,
+			Key:                 aws.String(obj.Key),
+			AccessControlPolicy: &types.AccessControlPolicy{Grants: acl.Grants, Owner: acl.Owner},
+		})
+		if err != nil {
+			return true, fmt.Errorf("header fixed but restoring the ACL failed, the object is now private: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// hasExtraGrants reports whether an object ACL grants anything beyond the owner's full control,
+// i.e. whether it differs from the private ACL a copy leaves behind
+func hasExtraGrants(acl *s3.GetObjectAclOutput) bool {
+	ownerID := ""
+	if acl.Owner != nil {
+		ownerID = aws.ToString(acl.Owner.ID)
+	}
+	for _, g := range acl.Grants {
+		if g.Grantee == nil || g.Permission != types.PermissionFullControl || aws.ToString(g.Grantee.ID) != ownerID {
+			return true
+		}
+	}
+	return false
+}
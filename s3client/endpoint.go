@@ -0,0 +1,55 @@
+package s3client
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// awsRegionHost matches the region part of regional AWS S3 hostnames such as
+// s3.eu-west-1.amazonaws.com, s3-eu-west-1.amazonaws.com or bucket.s3.dualstack.eu-west-1.amazonaws.com
+var awsRegionHost = regexp.MustCompile(`(?:^|\.)s3[.-](?:dualstack\.)?([a-z]{2}(?:-[a-z]+)+-\d)\.amazonaws\.com(?:\.cn)?$`)
+
+// endpointWarnings returns advisory messages about likely mismatches between endpoint and region.
+// None of them are fatal: the SDK is still allowed to try the request.
+func endpointWarnings(endpoint, region string) []string {
+	var warnings []string
+
+	if endpoint == "" {
+		if region == "" {
+			warnings = append(warnings, "no region configured and no endpoint set; requests to AWS will fail until one is provided")
+		}
+		return warnings
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return append(warnings, fmt.Sprintf("endpoint %q is not a valid URL (expected e.g. https://s3.example.com)", endpoint))
+	}
+	host := strings.ToLower(u.Hostname())
+
+	if !strings.HasSuffix(host, ".amazonaws.com") && !strings.HasSuffix(host, ".amazonaws.com.cn") {
+		switch region {
+		case "":
+			warnings = append(warnings, fmt.Sprintf("no region configured for custom endpoint %s; most providers expect one for signing (e.g. us-east-1 or auto)", host))
+		case "us-east-1":
+			warnings = append(warnings, fmt.Sprintf("region us-east-1 is only used for signing with custom endpoint %s; check that your provider expects it", host))
+		default:
+			warnings = append(warnings, fmt.Sprintf("region %s is only used for signing with custom endpoint %s", region, host))
+		}
+		return warnings
+	}
+
+	endpointRegion := "us-east-1"
+	if m := awsRegionHost.FindStringSubmatch(host); m != nil {
+		endpointRegion = m[1]
+	}
+	switch {
+	case region == "":
+		warnings = append(warnings, fmt.Sprintf("endpoint %s is in region %s but no region is configured", host, endpointRegion))
+	case region != endpointRegion:
+		warnings = append(warnings, fmt.Sprintf("endpoint %s is in region %s but region is set to %s; requests will likely fail with a signature or redirect error", host, endpointRegion, region))
+	}
+	return warnings
+}
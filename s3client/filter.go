@@ -0,0 +1,86 @@
+package s3client
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// KeyFilter selects files by glob patterns matched against their slash-separated path relative to
+// the directory being uploaded or synced, e.g. "src/app/main.go".
+//
+// Patterns use path.Match syntax within a path segment: * matches any run of characters except /,
+// ? a single character and [...] a character class. A segment that is exactly ** matches zero or
+// more whole segments, so **/*.log matches app.log and logs/2024/app.log. A pattern also matches
+// everything below a directory it matches: node_modules excludes node_modules/a/b.js, and
+// **/node_modules does so at any depth. Patterns are anchored at the top directory, so *.log only
+// matches .log files there.
+//
+// A path is selected when it matches no Exclude pattern and, if Include is not empty, at least one
+// Include pattern; exclusions always win.
+type KeyFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// Validate reports the first malformed pattern
+func (f KeyFilter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		for _, seg := range strings.Split(pattern, "/") {
+			if _, err := path.Match(seg, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Match reports whether the file at the relative path rel is selected
+func (f KeyFilter) Match(rel string) bool {
+	if matchAny(f.Exclude, rel) {
+		return false
+	}
+	return len(f.Include) == 0 || matchAny(f.Include, rel)
+}
+
+// Excluded reports whether rel matches an Exclude pattern, which also rules out everything below it
+func (f KeyFilter) Excluded(rel string) bool {
+	return matchAny(f.Exclude, rel)
+}
+
+// matchAny reports whether rel, or one of the directories leading to it, matches any of patterns
+func matchAny(patterns []string, rel string) bool {
+	segs := strings.Split(strings.Trim(rel, "/"), "/")
+	for _, pattern := range patterns {
+		pat := strings.Split(strings.Trim(pattern, "/"), "/")
+		for i := 1; i <= len(segs); i++ {
+			if matchSegments(pat, segs[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchSegments matches a pattern split at / against a path split the same way, letting a **
+// segment stand for any number of path segments
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,76 @@
+package s3client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestKeyFilterMatch(t *testing.T) {
+	tests := []struct {
+		filter KeyFilter
+		rel    string
+		want   bool
+	}{
+		{KeyFilter{}, "a/b.txt", true},
+		{KeyFilter{Exclude: []string{"**/*.log"}}, "app.log", false},
+		{KeyFilter{Exclude: []string{"**/*.log"}}, "logs/2024/app.log", false},
+		{KeyFilter{Exclude: []string{"**/*.log"}}, "logs/app.txt", true},
+		{KeyFilter{Exclude: []string{"*.log"}}, "logs/app.log", true},
+		{KeyFilter{Exclude: []string{".git"}}, ".git/config", false},
+		{KeyFilter{Exclude: []string{".git"}}, "sub/.git/config", true},
+		{KeyFilter{Exclude: []string{"**/node_modules"}}, "web/node_modules/x/index.js", false},
+		{KeyFilter{Exclude: []string{"a/**/c"}}, "a/c", false},
+		{KeyFilter{Exclude: []string{"a/**/c"}}, "a/b/b/c", false},
+		{KeyFilter{Exclude: []string{"a/**/c"}}, "a/b/cd", true},
+		{KeyFilter{Include: []string{"**/*.go"}}, "cmd/main.go", true},
+		{KeyFilter{Include: []string{"**/*.go"}}, "README.md", false},
+		{KeyFilter{Include: []string{"src"}}, "src/x/y.c", true},
+		{KeyFilter{Include: []string{"file?.[ch]"}}, "file1.c", true},
+		{KeyFilter{Include: []string{"file?.[ch]"}}, "file10.c", false},
+		{KeyFilter{Include: []string{"**/*.go"}, Exclude: []string{"**/*_test.go"}}, "pkg/a_test.go", false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.rel); got != tt.want {
+			t.Errorf("%+v.Match(%q) = %v, want %v", tt.filter, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestKeyFilterValidate(t *testing.T) {
+	if err := (KeyFilter{Exclude: []string{"**/[a-"}}).Validate(); err == nil {
+		t.Error("Validate accepted an unterminated character class")
+	}
+	if err := (KeyFilter{Include: []string{"**/*.go", "a/?/[bc]"}}).Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestPlanSyncFilter(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"index.html", "debug.log", ".git/HEAD", "web/node_modules/lib.js", "web/app.js"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := NewClient(&pagedS3{pageSize: 1000}, "", "")
+	filter := KeyFilter{Exclude: []string{".git", "**/node_modules", "**/*.log"}}
+	plan, err := client.PlanSync(context.Background(), dir, "site", false, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, item := range plan.Items {
+		keys = append(keys, item.Key)
+	}
+	if want := []string{"site/index.html", "site/web/app.js"}; !slices.Equal(keys, want) {
+		t.Errorf("planned keys = %v, want %v", keys, want)
+	}
+}
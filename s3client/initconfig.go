@@ -0,0 +1,131 @@
+package s3client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// configTemplate documents every key ReadConfig understands
+const configTemplate = `# s3-client configuration
+# Keys that are commented out are optional.
+
+# Credentials. Leave both out to use the default AWS credential chain
+# (environment variables, ~/.aws/credentials, -aws-profile).
+aws_access_key_id = "your_access_key_id"
+aws_secret_access_key = "your_secret_access_key"
+# aws_session_token = "only for temporary (STS) credentials"
+
+# Assume this IAM role with the credentials above before every operation
+# role_arn = "arn:aws:iam::123456789012:role/uploader"
+# role_session_name = "s3-client"
+# external_id = "only if the role's trust policy requires one"
+# MFA device of the base credentials; the token code is asked for on stdin
+# mfa_serial = "arn:aws:iam::123456789012:mfa/you"
+
+bucket = "your_bucket_name"
+# Required unless endpoint is set or AWS_REGION is exported
+region = "us-east-1"
+# Custom endpoint for S3-compatible services; leave out for AWS S3
+# endpoint = "https://s3.example.com"
+
+# Base URL printed for uploaded files
+returnurl = "https://your_bucket_name.s3.us-east-1.amazonaws.com"
+
+# Fill in endpoint, region and path style for a known service: r2, b2, minio or wasabi
+# provider = "r2"
+# Cloudflare account id, used by provider = "r2"
+# account_id = "your_account_id"
+
+# Default canned ACL for uploads, e.g. "private" or "public-read"; leave out to use the bucket default
+# acl = "public-read"
+
+# Default server-side encryption for uploads: "AES256" or "aws:kms"
+# sse = "aws:kms"
+# sse_kms_key_id = "your_kms_key_id"
+
+# Default Cache-Control header for uploads; -cache-control overrides it
+# cache_control = "public, max-age=31536000"
+
+# Trust an extra CA certificate (PEM), e.g. for a self-hosted endpoint with its own CA
+# ca_cert = "/path/to/ca.pem"
+# Skip certificate verification entirely (unsafe)
+# insecure = false
+
+# Give up on an operation after this long; "0" means no limit. -timeout overrides it.
+# timeout = "30s"
+
+# Retries of failed requests; retry_mode is "standard" or "adaptive"
+# max_retries = 3
+# retry_mode = "standard"
+
+# Multipart upload tuning: part size in MiB (at least 5) and parts sent in parallel.
+# Each upload holds up to part_size * upload_concurrency in memory.
+# part_size = 16
+# upload_concurrency = 5
+
+# Additional settings selected with -profile <name>; they take the same keys as above
+# [profiles.work]
+# aws_access_key_id = "..."
+# aws_secret_access_key = "..."
+# bucket = "work-bucket"
+# region = "eu-central-1"
+
+# Other buckets selected with -target <name>. Unlike a profile, a target only overrides the
+# keys it sets (usually bucket, region, endpoint and returnurl) and keeps the rest from above.
+# [targets.backup]
+# bucket = "backup-bucket"
+# region = "eu-central-1"
+# returnurl = "https://backup-bucket.s3.eu-central-1.amazonaws.com"
+`
+
+// DefaultConfigPath returns ~/.config/s3-client/s3config.toml
+func DefaultConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", "s3-client", "s3config.toml"), nil
+}
+
+// InitConfig writes a commented config template to path, or to DefaultConfigPath when path is empty,
+// creating its directory. An existing file is only replaced when overwrite is set; otherwise the error
+// wraps os.ErrExist. It returns the path written, or the path that already exists.
+func InitConfig(path string, overwrite bool) (string, error) {
+	if path == "" {
+		var err error
+		if path, err = DefaultConfigPath(); err != nil {
+			return "", fmt.Errorf("finding home directory: %w", err)
+		}
+	}
+
+	if ext := filepath.Ext(path); ext != ".toml" {
+		return "", fmt.Errorf("the template is TOML, so %s must end in .toml", path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("creating config directory: %w", err)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	// The file will hold credentials, so keep it private to the user
+	f, err := os.OpenFile(path, flags, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return path, fmt.Errorf("%s: %w; use -overwrite to replace it", path, os.ErrExist)
+	}
+	if err != nil {
+		return "", fmt.Errorf("creating config: %w", err)
+	}
+	if _, err := f.WriteString(configTemplate); err != nil {
+		f.Close()
+		return "", fmt.Errorf("writing config: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("writing config: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,315 @@
+package s3client
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ObjectInfo describes a single object returned by ListObjects
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	ETag         string    `json:"etag"`
+	StorageClass string    `json:"storageClass"`
+}
+
+// ListOptions controls what ListObjects returns
+type ListOptions struct {
+	// Prefix restricts the listing to keys starting with it; empty lists the whole bucket
+	Prefix string
+	// Delimiter groups keys sharing a prefix up to the delimiter (usually "/") into Listing.Prefixes
+	Delimiter string
+	// Sort orders the objects by "name", "size" or "date" (last modified), smallest or oldest first;
+	// empty keeps S3's key order. Reverse flips the order.
+	Sort    string
+	Reverse bool
+	// Limit keeps only the first this many entries (objects, plus prefixes when a delimiter is set);
+	// 0 lists everything. In key order the listing stops fetching once it has them. With Sort or
+	// Reverse the whole listing is fetched and sorted first, so e.g. size order keeps the smallest.
+	Limit int
+}
+
+// Listing is the result of ListObjects
+type Listing struct {
+	// Prefixes holds the folder-like common prefixes; only set when a delimiter is used
+	Prefixes []string     `json:"prefixes"`
+	Objects  []ObjectInfo `json:"objects"`
+	// Count and TotalBytes sum up Objects; folders in Prefixes are not included
+	Count      int64 `json:"count"`
+	TotalBytes int64 `json:"totalBytes"`
+	// Truncated is set when ListOptions.Limit cut the listing short and more entries exist
+	Truncated bool `json:"truncated"`
+}
+
+// maxListKeys is the most entries S3 returns in one ListObjectsV2 response
+const maxListKeys = 1000
+
+// ListObjects returns the objects (and, with a delimiter, the common prefixes) matching opts
+func (c *Client) ListObjects(ctx context.Context, opts ListOptions) (*Listing, error) {
+	input := &s3.ListObjectsV2Input{Bucket: &c.Bucket}
+	if opts.Prefix != "" {
+		input.Prefix = aws.String(opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = aws.String(opts.Delimiter)
+	}
+
+	var compare func(a, b ObjectInfo) int
+	switch opts.Sort {
+	case "", "name":
+		// S3 already returns keys in name order
+	case "size":
+		compare = func(a, b ObjectInfo) int { return cmp.Compare(a.Size, b.Size) }
+	case "date":
+		compare = func(a, b ObjectInfo) int { return a.LastModified.Compare(b.LastModified) }
+	default:
+		return nil, fmt.Errorf("unknown sort %q (want name, size or date)", opts.Sort)
+	}
+
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+
+	// Any other order than S3's needs every key before the first entries are known
+	fetchLimit := opts.Limit
+	if compare != nil || opts.Reverse {
+		fetchLimit = 0
+	}
+
+	listing := &Listing{Prefixes: []string{}, Objects: []ObjectInfo{}}
+	// Paginate by hand so the last request of a limited listing only asks for what is still missing
+	for entries := 0; ; {
+		if fetchLimit > 0 {
+			input.MaxKeys = aws.Int32(int32(min(fetchLimit-entries, maxListKeys)))
+		}
+		page, err := c.S3.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("listing files: %w", err)
+		}
+		for _, p := range page.CommonPrefixes {
+			if fetchLimit > 0 && entries == fetchLimit {
+				listing.Truncated = true
+				break
+			}
+			listing.Prefixes = append(listing.Prefixes, aws.ToString(p.Prefix))
+			entries++
+		}
+		for _, item := range page.Contents {
+			if fetchLimit > 0 && entries == fetchLimit {
+				listing.Truncated = true
+				break
+			}
+			listing.Objects = append(listing.Objects, ObjectInfo{
+				Key:          aws.ToString(item.Key),
+				Size:         aws.ToInt64(item.Size),
+				LastModified: aws.ToTime(item.LastModified),
+				ETag:         strings.Trim(aws.ToString(item.ETag), `"`),
+				StorageClass: string(item.StorageClass),
+			})
+			listing.Count++
+			listing.TotalBytes += aws.ToInt64(item.Size)
+			entries++
+		}
+		if !aws.ToBool(page.IsTruncated) || aws.ToString(page.NextContinuationToken) == "" {
+			break
+		}
+		if fetchLimit > 0 && entries >= fetchLimit {
+			listing.Truncated = true
+			break
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+
+	if compare != nil {
+		// Stable, so equal sizes or dates stay in key order
+		slices.SortStableFunc(listing.Objects, compare)
+	}
+	if opts.Reverse {
+		slices.Reverse(listing.Objects)
+		if opts.Sort == "" || opts.Sort == "name" {
+			slices.Reverse(listing.Prefixes)
+		}
+	}
+	if opts.Limit > 0 && len(listing.Prefixes)+len(listing.Objects) > opts.Limit {
+		listing.Truncated = true
+		prefixes := min(len(listing.Prefixes), opts.Limit)
+		listing.Prefixes = listing.Prefixes[:prefixes]
+		listing.Objects = listing.Objects[:opts.Limit-prefixes]
+		listing.Count, listing.TotalBytes = 0, 0
+		for _, obj := range listing.Objects {
+			listing.Count++
+			listing.TotalBytes += obj.Size
+		}
+	}
+	return listing, nil
+}
+
+// OutputOptions controls how ListFiles renders a listing
+type OutputOptions struct {
+	// Format is "text" for the human-readable listing or "json" for machine-readable output
+	Format string
+	// HumanSizes prints sizes as KiB/MiB/GiB in text output instead of raw bytes
+	HumanSizes bool
+	// NoSummary leaves out the "N objects, X total" footer of the text output
+	NoSummary bool
+}
+
+// ListFiles prints the objects matching opts. In JSON format this is an array of objects, or an object
+// with "prefixes" and "objects" when a delimiter is set.
+func (c *Client) ListFiles(ctx context.Context, opts ListOptions, out OutputOptions) error {
+	if out.Format != "text" && out.Format != "json" {
+		return unknownFormat(out.Format)
+	}
+
+	listing, err := c.ListObjects(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if out.Format == "json" {
+		if opts.Delimiter != "" {
+			return writeJSON(os.Stdout, listing)
+		}
+		return writeJSON(os.Stdout, listing.Objects)
+	}
+
+	if opts.Prefix != "" {
+		fmt.Printf("Files in bucket '%s' under '%s':\n", c.Bucket, opts.Prefix)
+	} else {
+		fmt.Printf("Files in bucket '%s':\n", c.Bucket)
+	}
+	for _, p := range listing.Prefixes {
+		fmt.Printf("- %s (directory)\n", p)
+	}
+
+	if !out.HumanSizes {
+		for _, obj := range listing.Objects {
+			fmt.Printf("- %s (Size: %d, Last modified: %s, Storage class: %s)\n",
+				obj.Key, obj.Size, obj.LastModified.Format("2006-01-02 15:04:05"), obj.StorageClass)
+		}
+	} else {
+		width := 0
+		for _, obj := range listing.Objects {
+			width = max(width, len(obj.Key))
+		}
+		for _, obj := range listing.Objects {
+			fmt.Printf("- %-*s  %10s  %s  %s\n",
+				width, obj.Key, formatBytes(obj.Size), obj.LastModified.Format("2006-01-02 15:04:05"), obj.StorageClass)
+		}
+	}
+
+	if !out.NoSummary {
+		note := ""
+		if listing.Truncated {
+			note = fmt.Sprintf(" (stopped at -limit %d, more exist)", opts.Limit)
+		}
+		fmt.Printf("%s, %s total%s\n", objectCount(listing.Count), formatBytes(listing.TotalBytes), note)
+	}
+	return nil
+}
+
+// objectCount formats n as "1 object" or "1,234 objects"
+func objectCount(n int64) string {
+	if n == 1 {
+		return "1 object"
+	}
+	return groupThousands(n) + " objects"
+}
+
+// groupThousands formats n with comma separators, e.g. 1234567 as "1,234,567"
+func groupThousands(n int64) string {
+	if n < 0 {
+		return "-" + groupThousands(-n)
+	}
+	s := strconv.FormatInt(n, 10)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// formatBytes formats n using binary units with one decimal place, e.g. "4.2 MiB". It is shared by
+// every text output that shows sizes, so they read the same everywhere.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 4; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTP"[exp])
+}
+
+// Usage sums up the objects under a prefix
+type Usage struct {
+	Count      int64 `json:"count"`
+	TotalBytes int64 `json:"totalBytes"`
+	// ByStorageClass breaks Count and TotalBytes down by storage class
+	ByStorageClass map[string]*ClassUsage `json:"byStorageClass"`
+}
+
+// ClassUsage is the part of a Usage in one storage class
+type ClassUsage struct {
+	Count      int64 `json:"count"`
+	TotalBytes int64 `json:"totalBytes"`
+}
+
+// BucketStats counts the objects under prefix and sums their sizes without keeping the keys in memory
+func (c *Client) BucketStats(ctx context.Context, prefix string) (count int64, totalBytes int64, err error) {
+	usage, err := c.BucketUsage(ctx, prefix)
+	if err != nil {
+		return 0, 0, err
+	}
+	return usage.Count, usage.TotalBytes, nil
+}
+
+// BucketUsage is BucketStats with a breakdown by storage class. It streams through the listing, so
+// memory use doesn't grow with the number of objects.
+func (c *Client) BucketUsage(ctx context.Context, prefix string) (*Usage, error) {
+	input := &s3.ListObjectsV2Input{Bucket: &c.Bucket}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	usage := &Usage{ByStorageClass: map[string]*ClassUsage{}}
+	paginator := s3.NewListObjectsV2Paginator(c.S3, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing files: %w", err)
+		}
+		for _, item := range page.Contents {
+			size := aws.ToInt64(item.Size)
+			usage.Count++
+			usage.TotalBytes += size
+
+			// Some S3-compatible endpoints leave the class out for standard storage
+			class := string(item.StorageClass)
+			if class == "" {
+				class = string(types.ObjectStorageClassStandard)
+			}
+			byClass := usage.ByStorageClass[class]
+			if byClass == nil {
+				byClass = &ClassUsage{}
+				usage.ByStorageClass[class] = byClass
+			}
+			byClass.Count++
+			byClass.TotalBytes += size
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,34 @@
+package s3client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/aws/smithy-go/logging"
+)
+
+// discardLogger is used when a Client has no Logger
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// log returns the client's logger, or one that drops everything when none is set
+func (c *Client) log() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return discardLogger
+}
+
+// sdkLogger forwards the AWS SDK's own log output, such as retry attempts, to slog at debug level
+type sdkLogger struct {
+	logger *slog.Logger
+}
+
+func (l sdkLogger) Logf(classification logging.Classification, format string, v ...interface{}) {
+	level := slog.LevelDebug
+	if classification == logging.Warn {
+		level = slog.LevelWarn
+	}
+	l.logger.Log(context.Background(), level, fmt.Sprintf(format, v...), "source", "aws-sdk")
+}
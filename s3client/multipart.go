@@ -0,0 +1,129 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// MultipartUpload describes a multipart upload that was started but never completed or aborted.
+// Its parts are stored, and billed, until it is aborted.
+type MultipartUpload struct {
+	Key       string    `json:"key"`
+	UploadID  string    `json:"uploadId"`
+	Initiated time.Time `json:"initiated"`
+	// Parts and Size describe the parts uploaded so far
+	Parts int   `json:"parts"`
+	Size  int64 `json:"size"`
+}
+
+// ListMultipartUploads returns the incomplete multipart uploads of keys starting with prefix, oldest first
+func (c *Client) ListMultipartUploads(ctx context.Context, prefix string) ([]MultipartUpload, error) {
+	input := &s3.ListMultipartUploadsInput{Bucket: &c.Bucket}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	uploads := []MultipartUpload{}
+	paginator := s3.NewListMultipartUploadsPaginator(c.S3, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing multipart uploads: %w", err)
+		}
+		for _, u := range page.Uploads {
+			upload := MultipartUpload{
+				Key:       aws.ToString(u.Key),
+				UploadID:  aws.ToString(u.UploadId),
+				Initiated: aws.ToTime(u.Initiated),
+			}
+			if err := c.countParts(ctx, &upload); err != nil {
+				return nil, err
+			}
+			uploads = append(uploads, upload)
+		}
+	}
+	return uploads, nil
+}
+
+// countParts fills in the number and total size of the parts of upload
+func (c *Client) countParts(ctx context.Context, upload *MultipartUpload) error {
+	paginator := s3.NewListPartsPaginator(c.S3, &s3.ListPartsInput{
+		Bucket:   &c.Bucket,
+		Key:      &upload.Key,
+		UploadId: &upload.UploadID,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("listing parts of %s: %w", upload.Key, err)
+		}
+		for _, p := range page.Parts {
+			upload.Parts++
+			upload.Size += aws.ToInt64(p.Size)
+		}
+	}
+	return nil
+}
+
+// AbortMultipartUpload aborts the multipart upload uploadID of key, deleting its parts
+func (c *Client) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	_, err := c.S3.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   &c.Bucket,
+		Key:      &key,
+		UploadId: &uploadID,
+	})
+	if err != nil {
+		return fmt.Errorf("aborting upload %s of %s: %w", uploadID, key, err)
+	}
+	return nil
+}
+
+// AbortUploads aborts every incomplete multipart upload under prefix that was initiated more than
+// olderThan ago (0 aborts all of them) after asking for confirmation, and returns the ones aborted.
+// A failure to abort one upload doesn't stop the others. With DryRun set the uploads are only printed.
+func (c *Client) AbortUploads(ctx context.Context, prefix string, olderThan time.Duration) ([]MultipartUpload, error) {
+	uploads, err := c.ListMultipartUploads(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	var stale []MultipartUpload
+	for _, u := range uploads {
+		if u.Initiated.Before(cutoff) {
+			stale = append(stale, u)
+		}
+	}
+
+	if c.DryRun {
+		for _, u := range stale {
+			fmt.Printf("Would abort: %s (Upload ID: %s, Initiated: %s, Size: %d)\n",
+				u.Key, u.UploadID, u.Initiated.Format("2006-01-02 15:04:05"), u.Size)
+		}
+		return stale, nil
+	}
+	if len(stale) == 0 {
+		return nil, nil
+	}
+	if !c.confirm(fmt.Sprintf("Abort %d incomplete upload(s)?", len(stale))) {
+		return nil, fmt.Errorf("abort cancelled by user")
+	}
+
+	var (
+		aborted []MultipartUpload
+		errs    []error
+	)
+	for _, u := range stale {
+		if err := c.AbortMultipartUpload(ctx, u.Key, u.UploadID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Printf("Aborted: %s (Upload ID: %s)\n", u.Key, u.UploadID)
+		aborted = append(aborted, u)
+	}
+	return aborted, errors.Join(errs...)
+}
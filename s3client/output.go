@@ -0,0 +1,203 @@
+package s3client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// WritePresigned renders presigned objects to w as "text" or "json"
+func WritePresigned(w io.Writer, objects []PresignedObject, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, objects)
+	case "text", "":
+		for _, obj := range objects {
+			fmt.Fprintf(w, "%s\t%s\n", obj.Key, obj.URL)
+		}
+		return nil
+	default:
+		return unknownFormat(format)
+	}
+}
+
+// WritePresignedRequest renders a presigned request to w: the URL alone as "text", or the method, URL
+// and headers to send as "json"
+func WritePresignedRequest(w io.Writer, req *PresignedRequest, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, req)
+	case "text", "":
+		_, err := fmt.Fprintln(w, req.URL)
+		return err
+	default:
+		return unknownFormat(format)
+	}
+}
+
+// WriteCount renders the object count and total size of a prefix to w: the count alone as "text", or
+// both as "json"
+func WriteCount(w io.Writer, count, totalBytes int64, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, struct {
+			Count      int64 `json:"count"`
+			TotalBytes int64 `json:"totalBytes"`
+		}{count, totalBytes})
+	case "text", "":
+		_, err := fmt.Fprintln(w, count)
+		return err
+	default:
+		return unknownFormat(format)
+	}
+}
+
+// WriteStat renders object metadata to w as a "key: value" block or as JSON. humanSizes prints the
+// size as KiB/MiB/GiB in text output; JSON always has the raw bytes.
+func WriteStat(w io.Writer, stat *ObjectStat, format string, humanSizes bool) error {
+	switch format {
+	case "json":
+		return writeJSON(w, stat)
+	case "text", "":
+		fmt.Fprintf(w, "Key: %s\n", stat.Key)
+		if humanSizes {
+			fmt.Fprintf(w, "Size: %s\n", formatBytes(stat.Size))
+		} else {
+			fmt.Fprintf(w, "Size: %d\n", stat.Size)
+		}
+		fmt.Fprintf(w, "Content-Type: %s\n", stat.ContentType)
+		fmt.Fprintf(w, "ETag: %s\n", stat.ETag)
+		fmt.Fprintf(w, "Last modified: %s\n", stat.LastModified.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(w, "Storage class: %s\n", stat.StorageClass)
+		names := make([]string, 0, len(stat.Metadata))
+		for name := range stat.Metadata {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "Metadata %s: %s\n", name, stat.Metadata[name])
+		}
+		return nil
+	default:
+		return unknownFormat(format)
+	}
+}
+
+// WriteTags renders object tags to w as sorted "key=value" lines or as a JSON object
+func WriteTags(w io.Writer, tags map[string]string, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, tags)
+	case "text", "":
+		names := make([]string, 0, len(tags))
+		for name := range tags {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s=%s\n", name, tags[name])
+		}
+		return nil
+	default:
+		return unknownFormat(format)
+	}
+}
+
+// WriteBuckets renders buckets to w as text lines or as a JSON array
+func WriteBuckets(w io.Writer, buckets []BucketInfo, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, buckets)
+	case "text", "":
+		for _, b := range buckets {
+			if b.Region != "" {
+				fmt.Fprintf(w, "- %s (Created: %s, Region: %s)\n", b.Name, b.CreationDate.Format("2006-01-02 15:04:05"), b.Region)
+			} else {
+				fmt.Fprintf(w, "- %s (Created: %s)\n", b.Name, b.CreationDate.Format("2006-01-02 15:04:05"))
+			}
+		}
+		return nil
+	default:
+		return unknownFormat(format)
+	}
+}
+
+// WriteUploads renders incomplete multipart uploads to w as text lines or as a JSON array
+func WriteUploads(w io.Writer, uploads []MultipartUpload, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, uploads)
+	case "text", "":
+		for _, u := range uploads {
+			fmt.Fprintf(w, "- %s (Upload ID: %s, Initiated: %s, Parts: %d, Size: %d)\n",
+				u.Key, u.UploadID, u.Initiated.Format("2006-01-02 15:04:05"), u.Parts, u.Size)
+		}
+		return nil
+	default:
+		return unknownFormat(format)
+	}
+}
+
+// WriteUsage renders a Usage to w as an "N objects, X total" line, optionally followed by one line per
+// storage class, or as JSON
+func WriteUsage(w io.Writer, usage *Usage, format string, byStorageClass bool) error {
+	switch format {
+	case "json":
+		if !byStorageClass {
+			return writeJSON(w, Usage{Count: usage.Count, TotalBytes: usage.TotalBytes})
+		}
+		return writeJSON(w, usage)
+	case "text", "":
+		fmt.Fprintf(w, "%s, %s total\n", objectCount(usage.Count), formatBytes(usage.TotalBytes))
+		if byStorageClass {
+			classes := make([]string, 0, len(usage.ByStorageClass))
+			for class := range usage.ByStorageClass {
+				classes = append(classes, class)
+			}
+			sort.Strings(classes)
+			for _, class := range classes {
+				u := usage.ByStorageClass[class]
+				fmt.Fprintf(w, "  %s: %s, %s\n", class, objectCount(u.Count), formatBytes(u.TotalBytes))
+			}
+		}
+		return nil
+	default:
+		return unknownFormat(format)
+	}
+}
+
+// WriteVersions renders object versions to w as text lines or as a JSON array
+func WriteVersions(w io.Writer, versions []ObjectVersion, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, versions)
+	case "text", "":
+		for _, v := range versions {
+			detail := fmt.Sprintf("Size: %d", v.Size)
+			if v.DeleteMarker {
+				detail = "delete marker"
+			}
+			latest := ""
+			if v.IsLatest {
+				latest = ", latest"
+			}
+			fmt.Fprintf(w, "- %s (Version: %s, %s, Last modified: %s%s)\n",
+				v.Key, v.VersionID, detail, v.LastModified.Format("2006-01-02 15:04:05"), latest)
+		}
+		return nil
+	default:
+		return unknownFormat(format)
+	}
+}
+
+// writeJSON writes v to w as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func unknownFormat(format string) error {
+	return fmt.Errorf("unknown output format %q (want text or json)", format)
+}
@@ -0,0 +1,158 @@
+package s3client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// MaxPresignExpiry is the longest lifetime SigV4 allows for a presigned URL
+const MaxPresignExpiry = 7 * 24 * time.Hour
+
+// PresignedObject is a listed object together with a time-limited download link
+type PresignedObject struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	URL     string    `json:"url"`
+	Expires time.Time `json:"expires"`
+}
+
+// PresignGetURL returns a URL that allows anyone holding it to download key until expiry elapses
+func (c *Client) PresignGetURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if err := validatePresignKey(key); err != nil {
+		return "", err
+	}
+	if err := validateExpiry(expiry); err != nil {
+		return "", err
+	}
+
+	if c.Presign == nil {
+		return "", fmt.Errorf("presigning is not available for this client")
+	}
+
+	req, err := c.Presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: &c.Bucket,
+		Key:    &key,
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fmt.Errorf("presigning %s: %w", key, err)
+	}
+	return req.URL, nil
+}
+
+// PresignedRequest is a presigned URL together with how it must be called
+type PresignedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// Headers lists the signed headers the caller must send unchanged (Host is omitted)
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// PresignPut presigns an upload to key valid until expiry elapses. When contentType is set it becomes
+// part of the signature, so the uploader must send exactly the same Content-Type header (listed in Headers).
+func (c *Client) PresignPut(ctx context.Context, key string, expiry time.Duration, contentType string) (*PresignedRequest, error) {
+	if err := validatePresignKey(key); err != nil {
+		return nil, err
+	}
+	if err := validateExpiry(expiry); err != nil {
+		return nil, err
+	}
+	if c.Presign == nil {
+		return nil, fmt.Errorf("presigning is not available for this client")
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: &c.Bucket,
+		Key:    &key,
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	req, err := c.Presign.PresignPutObject(ctx, input, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return nil, fmt.Errorf("presigning upload of %s: %w", key, err)
+	}
+
+	res := &PresignedRequest{Method: req.Method, URL: req.URL}
+	for name, values := range req.SignedHeader {
+		if strings.EqualFold(name, "Host") || len(values) == 0 {
+			continue
+		}
+		if res.Headers == nil {
+			res.Headers = make(map[string]string)
+		}
+		res.Headers[name] = values[0]
+	}
+	return res, nil
+}
+
+// PresignPutURL is like PresignPut but only returns the URL
+func (c *Client) PresignPutURL(ctx context.Context, key string, expiry time.Duration, contentType string) (string, error) {
+	req, err := c.PresignPut(ctx, key, expiry, contentType)
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+// ListPresigned lists the objects under prefix and presigns a download URL for each one.
+// To avoid presigning a whole bucket by accident either prefix or a positive limit is required.
+func (c *Client) ListPresigned(ctx context.Context, prefix string, limit int, expiry time.Duration) ([]PresignedObject, error) {
+	if prefix == "" && limit <= 0 {
+		return nil, fmt.Errorf("refusing to presign the whole bucket: set a prefix or a limit")
+	}
+	if err := validateExpiry(expiry); err != nil {
+		return nil, err
+	}
+
+	input := &s3.ListObjectsV2Input{Bucket: &c.Bucket}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	expires := time.Now().Add(expiry)
+	var objects []PresignedObject
+	paginator := s3.NewListObjectsV2Paginator(c.S3, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing files: %w", err)
+		}
+		for _, item := range page.Contents {
+			key := aws.ToString(item.Key)
+			url, err := c.PresignGetURL(ctx, key, expiry)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, PresignedObject{Key: key, Size: aws.ToInt64(item.Size), URL: url, Expires: expires})
+			if limit > 0 && len(objects) >= limit {
+				return objects, nil
+			}
+		}
+	}
+	return objects, nil
+}
+
+// validatePresignKey rejects keys that would sign a URL for the wrong object: an empty key, or one
+// starting with a slash, which S3 treats as part of the name (see UploadOptions.validate)
+func validatePresignKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("key must not be empty")
+	}
+	if strings.HasPrefix(key, "/") {
+		return fmt.Errorf("key %q must not start with a slash", key)
+	}
+	return nil
+}
+
+// validateExpiry checks that expiry is within the range SigV4 accepts
+func validateExpiry(expiry time.Duration) error {
+	if expiry < time.Second || expiry > MaxPresignExpiry {
+		return fmt.Errorf("expiry must be between 1s and %s, got %s", MaxPresignExpiry, expiry)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package s3client
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressInterval limits how often the progress bar is redrawn
+const progressInterval = 100 * time.Millisecond
+
+const progressBarWidth = 30
+
+// progressMu keeps bars from concurrent transfers from being written over each other mid-line
+var progressMu sync.Mutex
+
+// progress tracks the bytes moved by one transfer and draws a bar for it
+type progress struct {
+	w        io.Writer
+	label    string
+	total    int64 // 0 when the size isn't known in advance
+	n        int64
+	start    time.Time
+	lastDraw time.Time
+}
+
+func newProgress(w io.Writer, label string, total int64) *progress {
+	return &progress{w: w, label: label, total: total, start: time.Now()}
+}
+
+func (p *progress) add(n int) {
+	p.n += int64(n)
+	if now := time.Now(); now.Sub(p.lastDraw) >= progressInterval {
+		p.lastDraw = now
+		p.draw()
+	}
+}
+
+// done draws the final state and ends the line
+func (p *progress) done() {
+	p.draw()
+	progressMu.Lock()
+	fmt.Fprintln(p.w)
+	progressMu.Unlock()
+}
+
+func (p *progress) draw() {
+	var rate int64
+	if elapsed := time.Since(p.start).Seconds(); elapsed > 0 {
+		rate = int64(float64(p.n) / elapsed)
+	}
+
+	var line string
+	if p.total > 0 {
+		frac := min(float64(p.n)/float64(p.total), 1)
+		filled := int(frac * progressBarWidth)
+		bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+		line = fmt.Sprintf("%s [%s] %3.0f%% %s/%s %s/s", p.label, bar, frac*100,
+			formatBytes(p.n), formatBytes(p.total), formatBytes(rate))
+	} else {
+		line = fmt.Sprintf("%s %s %s/s", p.label, formatBytes(p.n), formatBytes(rate))
+	}
+
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	// Pad so a shorter line fully covers the previous one
+	fmt.Fprintf(p.w, "\r%-80s", line)
+}
+
+// progressReader counts the bytes read through it
+type progressReader struct {
+	r io.Reader
+	p *progress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.p.add(n)
+	return n, err
+}
+
+// progressReadSeeker is a progressReader that can also seek, so the uploader can still size the body
+// and rewind it to retry a request. Seeking moves the counter to the new offset.
+type progressReadSeeker struct {
+	progressReader
+	s io.Seeker
+}
+
+func (r *progressReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.s.Seek(offset, whence)
+	if err == nil {
+		r.p.n = pos
+	}
+	return pos, err
+}
+
+// withProgress wraps body so reading from it advances p. The result implements io.Seeker whenever body does.
+func withProgress(body io.Reader, p *progress) io.Reader {
+	pr := progressReader{r: body, p: p}
+	if s, ok := body.(io.Seeker); ok {
+		return &progressReadSeeker{progressReader: pr, s: s}
+	}
+	return &pr
+}
@@ -0,0 +1,77 @@
+package s3client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// providerPreset holds the settings an S3-compatible provider needs that users otherwise get wrong
+type providerPreset struct {
+	// defaultRegion is used when no region is configured
+	defaultRegion string
+	// endpoint builds the endpoint URL from the effective region and the account id
+	endpoint  func(region, accountID string) (string, error)
+	pathStyle bool
+}
+
+var providerPresets = map[string]providerPreset{
+	"r2": {
+		// R2 has no regions; "auto" is what it expects for signing
+		defaultRegion: "auto",
+		endpoint: func(region, accountID string) (string, error) {
+			if accountID == "" {
+				return "", fmt.Errorf("provider r2 needs account_id to build the endpoint")
+			}
+			return fmt.Sprintf("https://%s.r2.cloudflarestorage.com", accountID), nil
+		},
+	},
+	"b2": {
+		endpoint: func(region, accountID string) (string, error) {
+			if region == "" {
+				return "", fmt.Errorf("provider b2 needs the bucket's region (e.g. us-west-004) to build the endpoint")
+			}
+			return fmt.Sprintf("https://s3.%s.backblazeb2.com", region), nil
+		},
+	},
+	"minio": {
+		defaultRegion: "us-east-1",
+		endpoint: func(region, accountID string) (string, error) {
+			return "http://localhost:9000", nil
+		},
+		pathStyle: true,
+	},
+	"wasabi": {
+		defaultRegion: "us-east-1",
+		endpoint: func(region, accountID string) (string, error) {
+			return fmt.Sprintf("https://s3.%s.wasabisys.com", region), nil
+		},
+	},
+}
+
+// applyProvider fills in endpoint, region and path style for a known provider. Values that are
+// already set explicitly are left alone; path style can only be turned on, never off.
+func applyProvider(provider, accountID string, endpoint, region *string, pathStyle *bool) error {
+	preset, ok := providerPresets[strings.ToLower(provider)]
+	if !ok {
+		names := make([]string, 0, len(providerPresets))
+		for name := range providerPresets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown provider %q (supported: %s)", provider, strings.Join(names, ", "))
+	}
+
+	if *region == "" {
+		*region = preset.defaultRegion
+	}
+	if *endpoint == "" {
+		ep, err := preset.endpoint(*region, accountID)
+		if err != nil {
+			return err
+		}
+		*endpoint = ep
+	}
+	*pathStyle = *pathStyle || preset.pathStyle
+	return nil
+}
@@ -0,0 +1,92 @@
+package s3client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+// maxRateBurst bounds how many bytes a rate-limited transfer moves per wait, so the speed stays even
+const maxRateBurst = 64 * 1024
+
+// NewRateLimiter returns a limiter for Client.RateLimit allowing bytesPerSecond
+func NewRateLimiter(bytesPerSecond int64) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(min(bytesPerSecond, maxRateBurst)))
+}
+
+// rateUnits maps the unit suffixes ParseRate accepts to their size in bytes
+var rateUnits = map[string]float64{
+	"": 1, "b": 1,
+	"k": 1e3, "kb": 1e3, "m": 1e6, "mb": 1e6, "g": 1e9, "gb": 1e9,
+	"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30,
+}
+
+// ParseRate parses a transfer rate such as "5MB/s", "500KiB/s" or "1000000" into bytes per second.
+// KB, MB and GB (or K, M, G) are powers of 1000; KiB, MiB and GiB powers of 1024.
+func ParseRate(s string) (int64, error) {
+	num := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "/s")
+	i := strings.IndexFunc(num, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	unit := ""
+	if i >= 0 {
+		num, unit = num[:i], strings.TrimSpace(num[i:])
+	}
+	mult, ok := rateUnits[unit]
+	value, err := strconv.ParseFloat(num, 64)
+	if !ok || err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid rate %q: want a positive number with an optional unit, e.g. 5MB/s or 500KiB/s", s)
+	}
+	bytes := int64(value * mult)
+	if bytes < 1 {
+		return 0, fmt.Errorf("rate %q is below 1 byte per second", s)
+	}
+	return bytes, nil
+}
+
+// rateLimitedReader delays reads so that everything read through readers sharing limiter stays
+// below its rate
+type rateLimitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (r *rateLimitedReader) Read(b []byte) (int, error) {
+	if len(b) > r.limiter.Burst() {
+		b = b[:r.limiter.Burst()]
+	}
+	n, err := r.r.Read(b)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// rateLimitedReadSeeker is a rateLimitedReader that can also seek, so the uploader can still size
+// the body and rewind it to retry a request
+type rateLimitedReadSeeker struct {
+	rateLimitedReader
+	s io.Seeker
+}
+
+func (r *rateLimitedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.s.Seek(offset, whence)
+}
+
+// withRateLimit wraps body so reading from it is limited by c.RateLimit, and returns body unchanged
+// when there is no limit. The result implements io.Seeker whenever body does.
+func (c *Client) withRateLimit(ctx context.Context, body io.Reader) io.Reader {
+	if c.RateLimit == nil {
+		return body
+	}
+	lr := rateLimitedReader{ctx: ctx, r: body, limiter: c.RateLimit}
+	if s, ok := body.(io.Seeker); ok {
+		return &rateLimitedReadSeeker{rateLimitedReader: lr, s: s}
+	}
+	return &lr
+}
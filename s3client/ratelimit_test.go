@@ -0,0 +1,52 @@
+package s3client
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUploadRateLimit(t *testing.T) {
+	const (
+		bytesPerSecond = 1 << 20
+		size           = 320 << 10
+	)
+	fake := &recordingS3{}
+	client := NewClient(fake, "bucket", "")
+	client.RateLimit = NewRateLimiter(bytesPerSecond)
+
+	start := time.Now()
+	if _, err := client.UploadReader(context.Background(), bytes.NewReader(make([]byte, size)), "limited.bin", true, UploadOptions{}); err != nil {
+		t.Fatalf("UploadReader: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	// The initial burst goes through at once; the rest is held to the rate
+	want := time.Duration(float64(size-maxRateBurst) / bytesPerSecond * float64(time.Second))
+	if elapsed < want {
+		t.Errorf("upload of %d bytes at %d B/s took %s, want at least %s", size, bytesPerSecond, elapsed, want)
+	}
+	if got := len(fake.body["limited.bin"]); got != size {
+		t.Errorf("uploaded %d bytes, want %d", got, size)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := map[string]int64{
+		"5MB/s":    5_000_000,
+		"500KiB/s": 500 << 10,
+		"1.5M":     1_500_000,
+		"1000":     1000,
+	}
+	for in, want := range tests {
+		if got, err := ParseRate(in); err != nil || got != want {
+			t.Errorf("ParseRate(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "fast", "-1MB/s", "5XB/s"} {
+		if _, err := ParseRate(in); err == nil {
+			t.Errorf("ParseRate(%q) succeeded, want an error", in)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package s3client
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// newRetryer returns the retryer used for every request: exponential backoff with jitter on throttling,
+// 5xx and network errors, trying at most maxAttempts times (0 keeps the SDK default of 3). Errors such
+// as NoSuchKey or AccessDenied are returned at once. The adaptive mode additionally slows down the
+// client when the endpoint throttles it.
+func newRetryer(maxAttempts int, mode aws.RetryMode) aws.Retryer {
+	standard := func(o *retry.StandardOptions) {
+		if maxAttempts > 0 {
+			o.MaxAttempts = maxAttempts
+		}
+	}
+	if mode == aws.RetryModeAdaptive {
+		return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+			o.StandardOptions = append(o.StandardOptions, standard)
+		})
+	}
+	return retry.NewStandard(standard)
+}
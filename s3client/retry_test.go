@@ -0,0 +1,67 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// retryTestClient returns a client talking to handler with newRetryer's settings, minus the backoff delay
+func retryTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	api := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		Retryer:      retry.AddWithMaxBackoffDelay(newRetryer(3, aws.RetryModeStandard), time.Millisecond),
+	})
+	return NewClient(api, "bucket", "")
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	var requests atomic.Int32
+	client := retryTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Length", "5")
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	if _, err := client.StatObject(context.Background(), "file.txt"); err != nil {
+		t.Fatalf("StatObject: %v", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3 (two failures, then success)", got)
+	}
+}
+
+func TestRetryNotFoundIsNotRetried(t *testing.T) {
+	var requests atomic.Int32
+	client := retryTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	if _, err := client.StatObject(context.Background(), "missing.txt"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("StatObject error = %v, want ErrNotFound", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
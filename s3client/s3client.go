@@ -4,56 +4,130 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/spf13/viper"
+	"golang.org/x/time/rate"
 )
 
+// DefaultConcurrency is the number of parallel uploads used by UploadFiles when the caller passes 0
+const DefaultConcurrency = 4
+
+// DefaultDeleteWait is how long the CLI waits for a deleted object to disappear
+const DefaultDeleteWait = 2 * time.Minute
+
+// promptMu serializes interactive prompts so concurrent uploads don't interleave them on the terminal
+var promptMu sync.Mutex
+
+// S3API is the subset of the S3 API used by Client. *s3.Client satisfies it; tests can substitute a fake.
+type S3API interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
+	PutObjectTagging(ctx context.Context, params *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error)
+	GetObjectAcl(ctx context.Context, params *s3.GetObjectAclInput, optFns ...func(*s3.Options)) (*s3.GetObjectAclOutput, error)
+	PutObjectAcl(ctx context.Context, params *s3.PutObjectAclInput, optFns ...func(*s3.Options)) (*s3.PutObjectAclOutput, error)
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
+	ListMultipartUploads(ctx context.Context, params *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error)
+	ListParts(ctx context.Context, params *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error)
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+	ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error)
+}
+
+// PresignAPI is the subset of the S3 presign client used by Client
+type PresignAPI interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+	PresignPutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
 type Client struct {
-	S3        *s3.Client
+	S3        S3API
+	Presign   PresignAPI
 	Bucket    string
 	ReturnURL string
+	// Endpoint, Region and ForcePathStyle record the effective settings LoadClient built the S3 client
+	// with, after provider presets and the AWS environment were applied. Endpoint is empty for AWS S3.
+	// Changing them doesn't reconfigure the client.
+	Endpoint       string
+	Region         string
+	ForcePathStyle bool
+	// Defaults fills in upload options the caller leaves empty (e.g. from the config file)
+	Defaults UploadOptions
+	// AssumeYes answers every confirmation prompt with "y" instead of asking on stdin
+	AssumeYes bool
+	// Confirm asks the user a yes/no question, e.g. before overwriting an object, and reports whether they
+	// agreed. nil uses PromptYesNo on the terminal. Calls are serialized, so it is never called concurrently.
+	Confirm func(prompt string) bool
+	// DryRun makes every operation that changes the bucket (uploads, deletions, syncs, copies, moves,
+	// tagging, bucket creation) print what it would do instead
+	DryRun bool
+	// Progress receives a progress bar for uploads and downloads when set (typically os.Stderr)
+	Progress io.Writer
+	// PartSize is the multipart part size in bytes; 0 uses the SDK default (StreamPartSize for streams).
+	// Each upload buffers up to PartSize times UploadConcurrency bytes in memory.
+	PartSize int64
+	// UploadConcurrency is the number of parts of one upload sent in parallel; 0 uses the SDK default
+	UploadConcurrency int
+	// Timeout is the timeout config setting: how long a caller should allow a single operation to
+	// take, 0 meaning no limit. Client methods don't apply it themselves; the caller's context decides.
+	Timeout time.Duration
+	// RateLimit caps the combined speed of every upload and download made through the client, including
+	// parallel parts and files; nil means unlimited. See NewRateLimiter.
+	RateLimit *rate.Limiter
+	// Logger receives diagnostics such as endpoint resolution, retries and skipped uploads. Results
+	// (URLs, listings) are never logged. nil discards everything.
+	Logger *slog.Logger
 }
 
-// LoadClient initializes the S3 client, preferring config file but falling back to default AWS chain.
-func LoadClient(ctx context.Context, configPath string, forcePathStyle bool) (*Client, error) {
-	// Default config search
-	if configPath == "" {
-		homeDir, err := os.UserHomeDir()
-		if err == nil {
-			candidates := []string{
-				"s3config.toml",
-				filepath.Join(homeDir, ".config", "s3-client", "s3config.toml"),
-			}
-			for _, loc := range candidates {
-				if _, err := os.Stat(loc); err == nil {
-					configPath = loc
-					break
-				}
-			}
-		}
+// NewClient wraps an existing S3 API implementation. Presigning is only available when api is an *s3.Client
+// or Presign is set by the caller.
+func NewClient(api S3API, bucket, returnURL string) *Client {
+	c := &Client{
+		S3:        api,
+		Bucket:    bucket,
+		ReturnURL: returnURL,
 	}
+	if s3c, ok := api.(*s3.Client); ok {
+		c.Presign = s3.NewPresignClient(s3c)
+	}
+	return c
+}
 
-	var (
-		accessKey, secretKey, region, bucket, endpoint, returnURL string
-	)
+// LoadClient builds a client from a resolved configuration, typically from ReadConfig with flag values
+// merged on top. Without credentials in cfg the default AWS chain is used, with cfg.AWSProfile (if set)
+// picking the profile from the shared AWS config files (~/.aws/credentials, ~/.aws/config). With
+// cfg.RoleARN set those credentials are only used to assume that role. A provider
+// selects endpoint, region and path-style defaults for a known S3-compatible service; an explicit
+// endpoint and region still win. logger becomes the client's Logger and also receives the SDK's retry
+// messages at debug level; it may be nil.
+func LoadClient(ctx context.Context, cfg Config, logger *slog.Logger) (*Client, error) {
+	if logger == nil {
+		logger = discardLogger
+	}
 
-	if configPath != "" {
-		viper.SetConfigFile(configPath)
-		if err := viper.ReadInConfig(); err == nil {
-			accessKey = viper.GetString("aws_access_key_id")
-			secretKey = viper.GetString("aws_secret_access_key")
-			region = viper.GetString("region")
-			bucket = viper.GetString("bucket")
-			endpoint = viper.GetString("endpoint")
-			returnURL = viper.GetString("returnurl")
+	endpoint, region, forcePathStyle := cfg.Endpoint, cfg.Region, cfg.ForcePathStyle
+	if cfg.Provider != "" {
+		if err := applyProvider(cfg.Provider, cfg.AccountID, &endpoint, &region, &forcePathStyle); err != nil {
+			return nil, err
 		}
 	}
 
@@ -66,99 +140,142 @@ func LoadClient(ctx context.Context, configPath string, forcePathStyle bool) (*C
 	})
 
 	// Build config
-	var cfg aws.Config
-	var err error
-	if accessKey != "" && secretKey != "" {
-		cfg, err = config.LoadDefaultConfig(ctx,
-			config.WithRegion(region),
-			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")),
-			config.WithEndpointResolverWithOptions(customResolver),
-		)
-	} else {
-		cfg, err = config.LoadDefaultConfig(ctx,
-			config.WithRegion(region),
-			config.WithEndpointResolverWithOptions(customResolver),
-		)
+	loadOpts := []func(*config.LoadOptions) error{
+		config.WithRegion(region),
+		config.WithEndpointResolverWithOptions(customResolver),
 	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	if cfg.AccessKeyID != "" && cfg.SecretAccessKey != "" {
+		loadOpts = append(loadOpts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, cfg.SessionToken)))
+	} else if cfg.AWSProfile != "" {
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(cfg.AWSProfile))
 	}
 
-	s3client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.UsePathStyle = forcePathStyle
-	})
+	if cfg.MaxAttempts < 0 {
+		return nil, fmt.Errorf("max retries must not be negative")
+	}
+	if cfg.MaxAttempts > 0 || cfg.RetryMode != "" {
+		mode := aws.RetryModeStandard
+		if cfg.RetryMode != "" {
+			var err error
+			if mode, err = aws.ParseRetryMode(cfg.RetryMode); err != nil {
+				return nil, fmt.Errorf("retry mode %q: want standard or adaptive", cfg.RetryMode)
+			}
+		}
+		loadOpts = append(loadOpts, config.WithRetryer(func() aws.Retryer {
+			return newRetryer(cfg.MaxAttempts, mode)
+		}))
+	}
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		loadOpts = append(loadOpts,
+			config.WithLogger(sdkLogger{logger}),
+			config.WithClientLogMode(aws.LogRetries))
+	}
 
-	return &Client{
-		S3:        s3client,
-		Bucket:    bucket,
-		ReturnURL: returnURL,
-	}, nil
-}
+	if cfg.Insecure || cfg.CACert != "" {
+		httpClient, err := tlsHTTPClient(cfg.Insecure, cfg.CACert)
+		if err != nil {
+			return nil, err
+		}
+		loadOpts = append(loadOpts, config.WithHTTPClient(httpClient))
+	}
+	if cfg.Insecure {
+		logger.Warn("TLS certificate verification is disabled; the connection can be intercepted. Use -ca-cert to trust a self-signed certificate instead")
+	}
 
-// UploadFile uploads a file with overwrite confirmation
-func (c *Client) UploadFile(ctx context.Context, filePath, directory string, overwrite bool) (string, error) {
-	file, err := os.Open(filePath)
+	awsCfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
 	if err != nil {
-		return "", fmt.Errorf("opening file: %w", err)
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
-	defer file.Close()
 
-	fileInfo, _ := file.Stat()
-	key := fileInfo.Name()
-	if directory != "" {
-		dir := strings.Trim(directory, "/")
-		key = filepath.Join(dir, key)
+	if cfg.RoleARN != "" {
+		logger.Info("assuming role", "role", cfg.RoleARN)
+		awsCfg.Credentials = aws.NewCredentialsCache(assumeRoleProvider(awsCfg, cfg))
 	}
-	key = filepath.ToSlash(key)
 
-	// Check existence
-	_, err = c.S3.HeadObject(ctx, &s3.HeadObjectInput{
-		Bucket: &c.Bucket,
-		Key:    &key,
+	if err := validateConfig(cfg.Path, cfg.Profile, !cfg.NoBucket, cfg.Bucket, awsCfg.Region, endpoint, cfg.AccessKeyID, cfg.SecretAccessKey); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("loaded configuration", "config", cfg.Path, "profile", cfg.Profile, "provider", cfg.Provider,
+		"bucket", cfg.Bucket, "region", awsCfg.Region, "endpoint", endpoint, "pathStyle", forcePathStyle)
+	for _, w := range endpointWarnings(endpoint, awsCfg.Region) {
+		logger.Info(w)
+	}
+
+	s3client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.UsePathStyle = forcePathStyle
 	})
-	if err == nil && !overwrite {
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Printf("File already exists. Overwrite? [y/n] > ")
-		resp, _ := reader.ReadString('\n')
-		if strings.ToLower(strings.TrimSpace(resp)) != "y" {
-			return "", fmt.Errorf("upload cancelled by user")
-		}
+
+	client := NewClient(s3client, cfg.Bucket, cfg.ReturnURL)
+	client.Defaults = UploadOptions{ACL: cfg.ACL, SSE: cfg.SSE, SSEKMSKeyID: cfg.SSEKMSKeyID, CacheControl: cfg.CacheControl}
+	client.Logger = logger
+	client.Endpoint = endpoint
+	client.Region = awsCfg.Region
+	client.ForcePathStyle = forcePathStyle
+	client.Timeout = cfg.Timeout
+	client.PartSize = cfg.PartSizeMiB * MiB
+	client.UploadConcurrency = cfg.UploadConcurrency
+	if err := client.validateUploadTuning(); err != nil {
+		return nil, fmt.Errorf("%s: %w", cfg.Path, err)
 	}
+	return client, nil
+}
 
-	uploader := manager.NewUploader(c.S3)
-	_, err = uploader.Upload(ctx, &s3.PutObjectInput{
+// printWouldDelete reports what deleting key would remove, for dry runs
+func (c *Client) printWouldDelete(ctx context.Context, key string) error {
+	head, err := c.S3.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: &c.Bucket,
 		Key:    &key,
-		Body:   file,
 	})
-	if err != nil {
-		return "", fmt.Errorf("uploading file: %w", err)
+	switch {
+	case err == nil:
+		fmt.Printf("Would delete: %s (Size: %d)\n", key, aws.ToInt64(head.ContentLength))
+	case isNotFound(err):
+		fmt.Printf("Would delete: %s (does not exist)\n", key)
+	default:
+		return fmt.Errorf("checking object: %w", err)
 	}
+	return nil
+}
 
-	fullURL := fmt.Sprintf("%s/%s", strings.TrimRight(c.ReturnURL, "/"), strings.TrimLeft(key, "/"))
-	return fullURL, nil
+// confirm asks the user a yes/no question through Confirm (or on stdin) unless AssumeYes is set
+func (c *Client) confirm(prompt string) bool {
+	if c.AssumeYes {
+		return true
+	}
+	if c.Confirm == nil {
+		return PromptYesNo(prompt)
+	}
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	return c.Confirm(prompt)
 }
 
-// ListFiles lists all objects in the bucket
-func (c *Client) ListFiles(ctx context.Context) error {
-	paginator := s3.NewListObjectsV2Paginator(c.S3, &s3.ListObjectsV2Input{Bucket: &c.Bucket})
-	fmt.Printf("Files in bucket '%s':\n", c.Bucket)
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(ctx)
-		if err != nil {
-			return fmt.Errorf("listing files: %w", err)
-		}
-		for _, item := range page.Contents {
-			fmt.Printf("- %s (Size: %d, Last modified: %s)\n",
-				aws.ToString(item.Key), item.Size, item.LastModified.Format("2006-01-02 15:04:05"))
-		}
+// PromptYesNo asks the user a yes/no question on stdin and reports whether they answered "y".
+// When stdin is not a terminal (e.g. in a pipeline) it answers "n" instead of waiting for input.
+func PromptYesNo(prompt string) bool {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		fmt.Fprintf(os.Stderr, "%s [y/n] > n (stdin is not a terminal; use -yes to confirm)\n", prompt)
+		return false
 	}
-	return nil
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("%s [y/n] > ", prompt)
+	resp, _ := reader.ReadString('\n')
+	return strings.ToLower(strings.TrimSpace(resp)) == "y"
 }
 
-// DeleteFile deletes a file and waits until it is gone
-func (c *Client) DeleteFile(ctx context.Context, key string) error {
+// DeleteFile deletes a file and waits up to maxWait until it is gone. A maxWait of 0 skips the wait.
+// If the object still shows up after maxWait, the returned error wraps ErrWaitTimeout.
+func (c *Client) DeleteFile(ctx context.Context, key string, maxWait time.Duration) error {
 	key = strings.TrimPrefix(key, "/")
+	if c.DryRun {
+		return c.printWouldDelete(ctx, key)
+	}
 	_, err := c.S3.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: &c.Bucket,
 		Key:    &key,
@@ -167,11 +284,27 @@ func (c *Client) DeleteFile(ctx context.Context, key string) error {
 		return fmt.Errorf("deleting object: %w", err)
 	}
 
+	if maxWait <= 0 {
+		fmt.Printf("Deleted: %s\n", key)
+		return nil
+	}
+
+	// Don't outlive the caller's deadline
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = min(maxWait, time.Until(deadline))
+	}
+	if maxWait <= 0 {
+		return fmt.Errorf("%w %s to disappear; it was deleted but the deadline left no time to wait", ErrWaitTimeout, key)
+	}
+
 	waiter := s3.NewObjectNotExistsWaiter(c.S3)
 	if err := waiter.Wait(ctx, &s3.HeadObjectInput{
 		Bucket: &c.Bucket,
 		Key:    &key,
-	}, 0); err != nil {
+	}, maxWait); err != nil {
+		if waiterTimedOut(ctx, err) {
+			return fmt.Errorf("%w %s to disappear after %s; it was deleted but still shows up", ErrWaitTimeout, key, maxWait.Round(time.Second))
+		}
 		return fmt.Errorf("waiting for deletion: %w", err)
 	}
 
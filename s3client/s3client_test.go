@@ -0,0 +1,850 @@
+package s3client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeS3 records the calls made to it. Methods that a test doesn't override panic
+// through the nil embedded interface.
+type fakeS3 struct {
+	S3API
+
+	deleted []string
+	// putStarted, when set, receives a value each time PutObject or UploadPart is called
+	putStarted chan struct{}
+
+	mu      sync.Mutex
+	aborted []string
+}
+
+func (f *fakeS3) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.deleted = append(f.deleted, aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return nil, &types.NotFound{}
+}
+
+func TestDeleteFileTrimsKey(t *testing.T) {
+	fake := &fakeS3{}
+	client := NewClient(fake, "bucket", "")
+
+	if err := client.DeleteFile(context.Background(), "/dir1/filename.png", DefaultDeleteWait); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+
+	if len(fake.deleted) != 1 || fake.deleted[0] != "dir1/filename.png" {
+		t.Fatalf("DeleteObject keys = %q, want [dir1/filename.png]", fake.deleted)
+	}
+}
+
+// PutObject blocks until the request context is cancelled, simulating a slow upload
+func (f *fakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if f.putStarted != nil {
+		f.putStarted <- struct{}{}
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestUploadFilesCancel(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+
+	fake := &fakeS3{putStarted: make(chan struct{}, len(paths))}
+	client := NewClient(fake, "bucket", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	var results []UploadResult
+	go func() {
+		var err error
+		results, err = client.UploadFiles(ctx, paths, "", 2, true, UploadOptions{})
+		done <- err
+	}()
+
+	<-fake.putStarted
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("UploadFiles error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("UploadFiles did not return after cancellation")
+	}
+
+	for _, r := range results {
+		if r.Err == nil {
+			t.Errorf("upload of %s succeeded after cancellation", r.Path)
+		}
+	}
+}
+
+func (f *fakeS3) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+}
+
+// UploadPart blocks until the request context is cancelled, like PutObject
+func (f *fakeS3) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	if f.putStarted != nil {
+		f.putStarted <- struct{}{}
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// AbortMultipartUpload fails on a cancelled context like the real client, so only aborts made with a
+// live context are recorded
+func (f *fakeS3) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.aborted = append(f.aborted, aws.ToString(params.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestUploadFileCancelAbortsMultipart(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(p, make([]byte, 2*manager.MinUploadPartSize), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeS3{putStarted: make(chan struct{}, 2)}
+	client := NewClient(fake, "bucket", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.UploadFile(ctx, p, "", true, UploadOptions{})
+		done <- err
+	}()
+
+	<-fake.putStarted
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("UploadFile error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("UploadFile did not return after cancellation")
+	}
+
+	if len(fake.aborted) != 1 || fake.aborted[0] != "upload-1" {
+		t.Fatalf("aborted uploads = %q, want [upload-1]", fake.aborted)
+	}
+}
+
+// bucketlessS3 answers ListBuckets the way S3-compatible endpoints without that operation do
+type bucketlessS3 struct {
+	S3API
+}
+
+func (bucketlessS3) ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "NotImplemented", Message: "A header you provided implies functionality that is not implemented"}
+}
+
+func TestListBucketsNotSupported(t *testing.T) {
+	client := NewClient(bucketlessS3{}, "", "")
+	if _, err := client.ListBuckets(context.Background()); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("ListBuckets error = %v, want ErrNotSupported", err)
+	}
+}
+
+// recordingS3 stores the objects put into it
+type recordingS3 struct {
+	S3API
+
+	puts map[string]*s3.PutObjectInput
+	body map[string][]byte
+}
+
+func (r *recordingS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return nil, &types.NotFound{}
+}
+
+func (r *recordingS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	if r.puts == nil {
+		r.puts, r.body = map[string]*s3.PutObjectInput{}, map[string][]byte{}
+	}
+	r.puts[aws.ToString(params.Key)] = params
+	r.body[aws.ToString(params.Key)] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestUploadFileGzip(t *testing.T) {
+	dir := t.TempDir()
+	text := strings.Repeat("a line of log output\n", 100)
+	logPath := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(logPath, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pngPath := filepath.Join(dir, "image.png")
+	if err := os.WriteFile(pngPath, []byte("not really a png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &recordingS3{}
+	client := NewClient(fake, "bucket", "")
+	for _, p := range []string{logPath, pngPath} {
+		if _, err := client.UploadFile(context.Background(), p, "", true, UploadOptions{Gzip: true}); err != nil {
+			t.Fatalf("UploadFile(%s): %v", p, err)
+		}
+	}
+
+	put := fake.puts["app.log.gz"]
+	if put == nil {
+		t.Fatalf("uploaded keys = %v, want app.log.gz", fake.puts)
+	}
+	if got := aws.ToString(put.ContentEncoding); got != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", got)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(fake.body["app.log.gz"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(zr); err != nil || string(data) != text {
+		t.Errorf("decompressed body = %q, %v; want the file content", data, err)
+	}
+
+	// Already compressed formats keep their key and content
+	if put := fake.puts["image.png"]; put == nil || put.ContentEncoding != nil {
+		t.Errorf("image.png was not uploaded unchanged: %+v", put)
+	}
+}
+
+func TestUploadHeaders(t *testing.T) {
+	fake := &recordingS3{}
+	client := NewClient(fake, "bucket", "")
+	client.Defaults = UploadOptions{CacheControl: "public, max-age=300"}
+
+	opts := UploadOptions{ContentDisposition: `attachment; filename="a.txt"`}
+	if _, err := client.UploadReader(context.Background(), strings.NewReader("a"), "a.txt", true, opts); err != nil {
+		t.Fatal(err)
+	}
+	opts = UploadOptions{CacheControl: "no-store"}
+	if _, err := client.UploadReader(context.Background(), strings.NewReader("b"), "b.txt", true, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := fake.puts["a.txt"], fake.puts["b.txt"]
+	if got := aws.ToString(a.CacheControl); got != "public, max-age=300" {
+		t.Errorf("a.txt Cache-Control = %q, want the default", got)
+	}
+	if got := aws.ToString(a.ContentDisposition); got != `attachment; filename="a.txt"` {
+		t.Errorf("a.txt Content-Disposition = %q", got)
+	}
+	if got := aws.ToString(b.CacheControl); got != "no-store" || b.ContentDisposition != nil {
+		t.Errorf("b.txt Cache-Control, Content-Disposition = %q, %v; want no-store, unset", got, b.ContentDisposition)
+	}
+}
+
+func TestUploadFilesGzipKeys(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"app.log", "image.png"} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(strings.Repeat("data ", 50)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+
+	client := NewClient(&recordingS3{}, "bucket", "")
+	results, err := client.UploadFiles(context.Background(), paths, "logs", 1, true, UploadOptions{Gzip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, r := range results {
+		keys = append(keys, r.Key)
+	}
+	if want := []string{"logs/app.log.gz", "logs/image.png"}; !slices.Equal(keys, want) {
+		t.Errorf("result keys = %v, want %v", keys, want)
+	}
+}
+
+// existingS3 is a recordingS3 in which every object already exists
+type existingS3 struct {
+	recordingS3
+}
+
+func (e *existingS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func TestUploadConfirmCallback(t *testing.T) {
+	fake := &existingS3{}
+	client := NewClient(fake, "bucket", "")
+	var prompts []string
+	answer := false
+	client.Confirm = func(prompt string) bool {
+		prompts = append(prompts, prompt)
+		return answer
+	}
+
+	body := strings.NewReader("data")
+	if _, err := client.UploadReader(context.Background(), body, "file.txt", false, UploadOptions{}); err == nil {
+		t.Fatal("upload succeeded although the overwrite was declined")
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "file.txt") {
+		t.Fatalf("prompts = %q, want one about file.txt", prompts)
+	}
+	if len(fake.puts) != 0 {
+		t.Fatalf("object was uploaded after the overwrite was declined")
+	}
+
+	answer = true
+	if _, err := client.UploadReader(context.Background(), strings.NewReader("data"), "file.txt", false, UploadOptions{}); err != nil {
+		t.Fatalf("UploadReader: %v", err)
+	}
+	if fake.puts["file.txt"] == nil {
+		t.Fatal("object was not uploaded after the overwrite was confirmed")
+	}
+}
+
+func TestDownloadPath(t *testing.T) {
+	tests := []struct {
+		prefix, key, want string
+	}{
+		{"photos/", "photos/2024/cat.png", filepath.Join("dest", "2024", "cat.png")},
+		{"photos", "photos/cat.png", filepath.Join("dest", "photos", "cat.png")},
+		{"photos/cat.png", "photos/cat.png", filepath.Join("dest", "cat.png")},
+		{"", "a/b.txt", filepath.Join("dest", "a", "b.txt")},
+	}
+	for _, tt := range tests {
+		got, err := downloadPath("dest", tt.prefix, tt.key)
+		if err != nil || got != tt.want {
+			t.Errorf("downloadPath(%q, %q) = %q, %v; want %q", tt.prefix, tt.key, got, err, tt.want)
+		}
+	}
+
+	for _, key := range []string{"photos/../../etc/passwd", "photos/../x", "photos/a/../../../x"} {
+		if got, err := downloadPath("dest", "photos/", key); err == nil {
+			t.Errorf("downloadPath(%q) = %q, want an error", key, got)
+		}
+	}
+}
+
+// pagedS3 serves keys k0..k(n-1) in pages of at most pageSize, honouring MaxKeys, and records the
+// MaxKeys of every request
+type pagedS3 struct {
+	S3API
+
+	n, pageSize int
+	maxKeys     []int32
+}
+
+func (p *pagedS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	start := 0
+	if params.ContinuationToken != nil {
+		fmt.Sscan(aws.ToString(params.ContinuationToken), &start)
+	}
+	size := p.pageSize
+	if params.MaxKeys != nil {
+		p.maxKeys = append(p.maxKeys, *params.MaxKeys)
+		size = min(size, int(*params.MaxKeys))
+	}
+	out := &s3.ListObjectsV2Output{}
+	end := min(start+size, p.n)
+	for i := start; i < end; i++ {
+		out.Contents = append(out.Contents, types.Object{Key: aws.String(fmt.Sprintf("k%d", i)), Size: aws.Int64(10)})
+	}
+	if end < p.n {
+		out.IsTruncated = aws.Bool(true)
+		out.NextContinuationToken = aws.String(fmt.Sprint(end))
+	}
+	return out, nil
+}
+
+func TestListObjectsLimit(t *testing.T) {
+	fake := &pagedS3{n: 2500, pageSize: 1000}
+	client := NewClient(fake, "", "")
+	listing, err := client.ListObjects(context.Background(), ListOptions{Limit: 1200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listing.Count != 1200 || listing.TotalBytes != 12000 || !listing.Truncated {
+		t.Errorf("Count, TotalBytes, Truncated = %d, %d, %v, want 1200, 12000, true", listing.Count, listing.TotalBytes, listing.Truncated)
+	}
+	if want := []int32{1000, 200}; !slices.Equal(fake.maxKeys, want) {
+		t.Errorf("MaxKeys per request = %v, want %v", fake.maxKeys, want)
+	}
+
+	// A limit beyond the bucket lists everything and isn't reported as truncated
+	listing, err = client.ListObjects(context.Background(), ListOptions{Limit: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listing.Count != 2500 || listing.Truncated {
+		t.Errorf("Count, Truncated = %d, %v, want 2500, false", listing.Count, listing.Truncated)
+	}
+
+	// In any other order the limit applies after sorting the whole listing
+	listing, err = client.ListObjects(context.Background(), ListOptions{Limit: 3, Reverse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, obj := range listing.Objects {
+		keys = append(keys, obj.Key)
+	}
+	if want := []string{"k2499", "k2498", "k2497"}; !slices.Equal(keys, want) || listing.Count != 3 || !listing.Truncated {
+		t.Errorf("keys, Count, Truncated = %v, %d, %v; want %v, 3, true", keys, listing.Count, listing.Truncated, want)
+	}
+}
+
+func TestDryRunChangesNothing(t *testing.T) {
+	// Every S3 call panics through the nil embedded interface, so any request fails the test
+	client := NewClient(&fakeS3{}, "bucket", "")
+	client.DryRun = true
+	ctx := context.Background()
+
+	if err := client.MoveObject(ctx, "a.txt", "b.txt"); err != nil {
+		t.Errorf("MoveObject: %v", err)
+	}
+	if err := client.CopyObject(ctx, "a.txt", "b.txt"); err != nil {
+		t.Errorf("CopyObject: %v", err)
+	}
+	if err := client.SetTags(ctx, "a.txt", map[string]string{"k": "v"}); err != nil {
+		t.Errorf("SetTags: %v", err)
+	}
+	if err := client.CreateBucket(ctx, "new-bucket", "eu-west-1"); err != nil {
+		t.Errorf("CreateBucket: %v", err)
+	}
+}
+
+// lingeringS3 deletes nothing for real: every object keeps showing up, or HeadObject fails with headErr
+type lingeringS3 struct {
+	S3API
+
+	headErr error
+}
+
+func (l *lingeringS3) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (l *lingeringS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if l.headErr != nil {
+		return nil, l.headErr
+	}
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func TestWaitForObjectTimeout(t *testing.T) {
+	client := NewClient(&fakeS3{}, "bucket", "")
+	_, err := client.WaitForObject(context.Background(), "k", 300*time.Millisecond, 20*time.Millisecond, 100*time.Millisecond)
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("WaitForObject error = %v, want ErrWaitTimeout", err)
+	}
+
+	denied := &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}
+	client = NewClient(&lingeringS3{headErr: denied}, "bucket", "")
+	_, err = client.WaitForObject(context.Background(), "k", 300*time.Millisecond, 20*time.Millisecond, 100*time.Millisecond)
+	if err == nil || errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("WaitForObject error = %v, want the AccessDenied error", err)
+	}
+}
+
+func TestDeleteFileWaitTimeout(t *testing.T) {
+	client := NewClient(&lingeringS3{}, "bucket", "")
+	// Shorter than the waiter's 5s minimum delay, so it gives up well before maxWait has passed
+	if err := client.DeleteFile(context.Background(), "k", time.Second); !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("DeleteFile error = %v, want ErrWaitTimeout", err)
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if err := client.DeleteFile(ctx, "k", time.Minute); !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("DeleteFile past the deadline = %v, want ErrWaitTimeout", err)
+	}
+}
+
+// emptyBucketS3 is a recordingS3 whose bucket lists as empty
+type emptyBucketS3 struct {
+	recordingS3
+}
+
+func (e *emptyBucketS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return &s3.ListObjectsV2Output{}, nil
+}
+
+func TestSyncUploadOptions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fake := &emptyBucketS3{}
+	client := NewClient(fake, "bucket", "")
+	opts := UploadOptions{Key: "ignored", SSE: "aws:kms", StorageClass: "STANDARD_IA"}
+	if _, err := client.Sync(context.Background(), dir, "backup", false, KeyFilter{}, opts); err != nil {
+		t.Fatal(err)
+	}
+	put := fake.puts["backup/a.txt"]
+	if put == nil {
+		t.Fatalf("backup/a.txt was not uploaded; got %v", slices.Collect(maps.Keys(fake.puts)))
+	}
+	if put.ServerSideEncryption != "aws:kms" || put.StorageClass != "STANDARD_IA" {
+		t.Errorf("SSE, storage class = %q, %q; want aws:kms, STANDARD_IA", put.ServerSideEncryption, put.StorageClass)
+	}
+
+	if _, err := client.Sync(context.Background(), dir, "backup", false, KeyFilter{}, UploadOptions{Gzip: true}); err == nil {
+		t.Error("Sync accepted Gzip")
+	}
+}
+
+// publicGzipS3 holds a single public, KMS-encrypted, gzip-compressed object without Content-Encoding
+type publicGzipS3 struct {
+	S3API
+
+	copied *s3.CopyObjectInput
+	acl    *s3.PutObjectAclInput
+}
+
+func (p *publicGzipS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return &s3.ListObjectsV2Output{Contents: []types.Object{{Key: aws.String("app.js"), Size: aws.Int64(100)}}}, nil
+}
+
+func (p *publicGzipS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return &s3.HeadObjectOutput{
+		ContentType:          aws.String("text/javascript"),
+		StorageClass:         types.StorageClassStandardIa,
+		ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+		SSEKMSKeyId:          aws.String("key-1"),
+	}, nil
+}
+
+func (p *publicGzipS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(gzipMagic))}, nil
+}
+
+func (p *publicGzipS3) GetObjectAcl(ctx context.Context, params *s3.GetObjectAclInput, optFns ...func(*s3.Options)) (*s3.GetObjectAclOutput, error) {
+	owner := &types.Owner{ID: aws.String("owner")}
+	return &s3.GetObjectAclOutput{Owner: owner, Grants: []types.Grant{
+		{Grantee: &types.Grantee{ID: owner.ID, Type: types.TypeCanonicalUser}, Permission: types.PermissionFullControl},
+		{Grantee: &types.Grantee{URI: aws.String("http://acs.amazonaws.com/groups/global/AllUsers"), Type: types.TypeGroup}, Permission: types.PermissionRead},
+	}}, nil
+}
+
+func (p *publicGzipS3) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	p.copied = params
+	return &s3.CopyObjectOutput{}, nil
+}
+
+func (p *publicGzipS3) PutObjectAcl(ctx context.Context, params *s3.PutObjectAclInput, optFns ...func(*s3.Options)) (*s3.PutObjectAclOutput, error) {
+	p.acl = params
+	return &s3.PutObjectAclOutput{}, nil
+}
+
+func TestFixGzipEncodingKeepsObjectSettings(t *testing.T) {
+	fake := &publicGzipS3{}
+	client := NewClient(fake, "bucket", "")
+	res, err := client.FixGzipEncoding(context.Background(), "", 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Fixed) != 1 || res.Checked != 1 || res.Skipped != 0 {
+		t.Errorf("result = %+v, want 1 checked and fixed", res)
+	}
+	cp := fake.copied
+	if cp == nil || aws.ToString(cp.ContentEncoding) != "gzip" {
+		t.Fatalf("object was not copied with Content-Encoding gzip: %+v", cp)
+	}
+	if cp.StorageClass != types.StorageClassStandardIa || cp.ServerSideEncryption != types.ServerSideEncryptionAwsKms || aws.ToString(cp.SSEKMSKeyId) != "key-1" {
+		t.Errorf("copy storage class, SSE, KMS key = %q, %q, %q", cp.StorageClass, cp.ServerSideEncryption, aws.ToString(cp.SSEKMSKeyId))
+	}
+	if fake.acl == nil || len(fake.acl.AccessControlPolicy.Grants) != 2 {
+		t.Errorf("public-read ACL was not restored: %+v", fake.acl)
+	}
+}
+
+// plainObjectsS3 lists one object too small to check and two that aren't gzip compressed
+type plainObjectsS3 struct {
+	S3API
+}
+
+func (plainObjectsS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return &s3.ListObjectsV2Output{Contents: []types.Object{
+		{Key: aws.String("empty"), Size: aws.Int64(0)},
+		{Key: aws.String("a.txt"), Size: aws.Int64(10)},
+		{Key: aws.String("b.txt"), Size: aws.Int64(10)},
+	}}, nil
+}
+
+func (plainObjectsS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func (plainObjectsS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("pl"))}, nil
+}
+
+func TestFixGzipEncodingCounts(t *testing.T) {
+	res, err := NewClient(plainObjectsS3{}, "bucket", "").FixGzipEncoding(context.Background(), "", 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Checked != 3 || res.Skipped != 3 || len(res.Fixed) != 0 {
+		t.Errorf("result = %+v, want 3 checked, 3 skipped", res)
+	}
+}
+
+// checksumS3 reports checksum as the stored SHA-256 of every object
+type checksumS3 struct {
+	S3API
+
+	checksum string
+}
+
+func (c checksumS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return &s3.HeadObjectOutput{ChecksumSHA256: aws.String(c.checksum)}, nil
+}
+
+func TestVerifyMultipartChecksum(t *testing.T) {
+	data := []byte("0123456789")
+	_, d, err := digestBody(bytes.NewReader(data), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// S3's composite checksum: SHA-256 over the part digests of "0123", "4567" and "89"
+	h := sha256.New()
+	for _, part := range []string{"0123", "4567", "89"} {
+		sum := sha256.Sum256([]byte(part))
+		h.Write(sum[:])
+	}
+	want := base64.StdEncoding.EncodeToString(h.Sum(nil)) + "-3"
+	if got := d.parts.composite(); got != want {
+		t.Fatalf("composite = %q, want %q", got, want)
+	}
+
+	if err := NewClient(checksumS3{checksum: want}, "bucket", "").verifyUpload(context.Background(), "k", d); err != nil {
+		t.Errorf("verifyUpload with the matching checksum: %v", err)
+	}
+	corrupt := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size)) + "-3"
+	if err := NewClient(checksumS3{checksum: corrupt}, "bucket", "").verifyUpload(context.Background(), "k", d); err == nil {
+		t.Error("verifyUpload accepted a different composite checksum")
+	}
+}
+
+// missingS3 has no objects at all
+type missingS3 struct {
+	S3API
+}
+
+func (missingS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return nil, &types.NoSuchKey{}
+}
+
+func TestDownloadRangeFailureKeepsDest(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "notes.txt")
+	original := []byte("my own notes\n")
+	if err := os.WriteFile(dest, original, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(missingS3{}, "bucket", "")
+	if _, err := client.DownloadRange(context.Background(), "missing-key", dest, 0, -1); err == nil {
+		t.Fatal("DownloadRange of a missing key succeeded")
+	}
+	if data, err := os.ReadFile(dest); err != nil || !bytes.Equal(data, original) {
+		t.Errorf("dest after a failed download = %q, %v; want it unchanged", data, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only dest (temporary file left behind?)", len(entries))
+	}
+}
+
+// gzipSourceS3 holds one gzip-encoded, tagged object with custom headers
+type gzipSourceS3 struct {
+	S3API
+}
+
+func (gzipSourceS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return &s3.HeadObjectOutput{ContentLength: aws.Int64(4)}, nil
+}
+
+func (gzipSourceS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{
+		Body:            io.NopCloser(strings.NewReader("data")),
+		ContentLength:   aws.Int64(4),
+		ContentType:     aws.String("text/css"),
+		ContentEncoding: aws.String("gzip"),
+		CacheControl:    aws.String("max-age=60"),
+		Metadata:        map[string]string{"owner": "web"},
+		StorageClass:    types.StorageClassStandardIa,
+		TagCount:        aws.Int32(1),
+	}, nil
+}
+
+func (gzipSourceS3) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	return &s3.GetObjectTaggingOutput{TagSet: []types.Tag{{Key: aws.String("team"), Value: aws.String("web")}}}, nil
+}
+
+func TestCopyToBucketStreamedKeepsHeaders(t *testing.T) {
+	fake := &recordingS3{}
+	source := NewClient(gzipSourceS3{}, "src", "")
+	target := NewClient(fake, "dst", "")
+	res, err := source.CopyToBucket(context.Background(), "site.css", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Method != "streamed" {
+		t.Fatalf("method = %q, want streamed", res.Method)
+	}
+	put := fake.puts["site.css"]
+	if put == nil {
+		t.Fatal("site.css was not uploaded to the target")
+	}
+	if aws.ToString(put.ContentEncoding) != "gzip" || aws.ToString(put.CacheControl) != "max-age=60" || aws.ToString(put.ContentType) != "text/css" {
+		t.Errorf("headers = %q, %q, %q; want gzip, max-age=60, text/css",
+			aws.ToString(put.ContentEncoding), aws.ToString(put.CacheControl), aws.ToString(put.ContentType))
+	}
+	if put.Metadata["owner"] != "web" || put.StorageClass != types.StorageClassStandardIa || aws.ToString(put.Tagging) != "team=web" {
+		t.Errorf("metadata, storage class, tagging = %v, %q, %q", put.Metadata, put.StorageClass, aws.ToString(put.Tagging))
+	}
+}
+
+// kmsBucketS3 lists one SSE-KMS encrypted object, whose ETag is not the MD5 of its content
+type kmsBucketS3 struct {
+	S3API
+
+	lastModified time.Time
+}
+
+func (k kmsBucketS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return &s3.ListObjectsV2Output{Contents: []types.Object{{
+		Key:          aws.String("a.txt"),
+		Size:         aws.Int64(5),
+		ETag:         aws.String(`"0123456789abcdef0123456789abcdef"`),
+		LastModified: aws.Time(k.lastModified),
+	}}}, nil
+}
+
+func (k kmsBucketS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return &s3.HeadObjectOutput{ServerSideEncryption: types.ServerSideEncryptionAwsKms}, nil
+}
+
+func TestPlanSyncKMSObjectUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(kmsBucketS3{lastModified: time.Now().Add(time.Hour)}, "bucket", "")
+	plan, err := client.PlanSync(context.Background(), dir, "", false, KeyFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Items) != 1 || plan.Items[0].Action != SyncSkip {
+		t.Errorf("plan = %+v, want a.txt skipped as unchanged", plan.Items)
+	}
+
+	// A local file newer than the object is still uploaded
+	client = NewClient(kmsBucketS3{lastModified: time.Now().Add(-time.Hour)}, "bucket", "")
+	if plan, err = client.PlanSync(context.Background(), dir, "", false, KeyFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Items) != 1 || plan.Items[0].Action != SyncUpload || plan.Items[0].Reason != "mtime newer" {
+		t.Errorf("plan = %+v, want a.txt uploaded as newer", plan.Items)
+	}
+}
+
+func TestWriteCount(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCount(&buf, 3, 2048, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"count\": 3,\n  \"totalBytes\": 2048\n}\n"; buf.String() != want {
+		t.Errorf("json output = %q, want %q", buf.String(), want)
+	}
+	if err := WriteCount(&buf, 3, 2048, "yaml"); err == nil {
+		t.Error("yaml format was accepted")
+	}
+}
+
+func TestDownloadPrefixKeepsDeclinedFiles(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(existing, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress bytes.Buffer
+	client := NewClient(plainObjectsS3{}, "bucket", "")
+	client.Progress = &progress
+	var asked []string
+	client.Confirm = func(prompt string) bool {
+		asked = append(asked, prompt)
+		return false
+	}
+	if err := client.DownloadPrefix(context.Background(), "", dir, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(existing); string(data) != "old" {
+		t.Errorf("a.txt = %q, want it left alone after declining", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "b.txt")); string(data) != "pl" {
+		t.Errorf("b.txt = %q, want it downloaded", data)
+	}
+	if len(asked) != 1 {
+		t.Errorf("asked %q, want one prompt for a.txt", asked)
+	}
+	if progress.Len() != 0 {
+		t.Errorf("progress bars were drawn with concurrency 2: %q", progress.String())
+	}
+}
+
+func TestPresignRejectsBadKeys(t *testing.T) {
+	client := NewClient(&fakeS3{}, "bucket", "")
+	for _, key := range []string{"", "/a.txt"} {
+		// The fake has no presigner, so only the key check can produce a "key" error
+		if _, err := client.PresignGetURL(context.Background(), key, time.Hour); err == nil || !strings.Contains(err.Error(), "key") {
+			t.Errorf("PresignGetURL(%q) error = %v, want the key rejected", key, err)
+		}
+		if _, err := client.PresignPut(context.Background(), key, time.Hour, ""); err == nil || !strings.Contains(err.Error(), "key") {
+			t.Errorf("PresignPut(%q) error = %v, want the key rejected", key, err)
+		}
+	}
+}
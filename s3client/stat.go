@@ -0,0 +1,76 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// ErrNotFound is returned (wrapped) when the requested object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ObjectStat holds the metadata HeadObject reports for an object
+type ObjectStat struct {
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	ContentType  string            `json:"contentType"`
+	ETag         string            `json:"etag"`
+	LastModified time.Time         `json:"lastModified"`
+	StorageClass string            `json:"storageClass"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// StatObject returns the metadata of key without downloading it
+func (c *Client) StatObject(ctx context.Context, key string) (*ObjectStat, error) {
+	key = strings.TrimPrefix(key, "/")
+	out, err := c.S3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &c.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("reading object metadata: %w", err)
+	}
+
+	// HeadObject leaves the storage class empty for STANDARD objects
+	storageClass := string(out.StorageClass)
+	if storageClass == "" {
+		storageClass = string(types.StorageClassStandard)
+	}
+	return &ObjectStat{
+		Key:          key,
+		Size:         aws.ToInt64(out.ContentLength),
+		ContentType:  aws.ToString(out.ContentType),
+		ETag:         strings.Trim(aws.ToString(out.ETag), `"`),
+		LastModified: aws.ToTime(out.LastModified),
+		StorageClass: storageClass,
+		Metadata:     out.Metadata,
+	}, nil
+}
+
+// isNotFound reports whether err is S3 saying the object doesn't exist. HeadObject has no response
+// body, so depending on the provider this shows up as NotFound, NoSuchKey or a bare 404 code.
+func isNotFound(err error) bool {
+	var notFound *types.NotFound
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &notFound) || errors.As(err, &noSuchKey) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NotFound", "NoSuchKey", "404":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,327 @@
+package s3client
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// SyncAction is what a sync would do with a single key
+type SyncAction string
+
+const (
+	SyncUpload SyncAction = "upload"
+	SyncDelete SyncAction = "delete"
+	SyncSkip   SyncAction = "skip"
+)
+
+// SyncItem is one planned step of a sync, with the reason it was chosen
+type SyncItem struct {
+	Action SyncAction `json:"action"`
+	Key    string     `json:"key"`
+	Path   string     `json:"path,omitempty"`
+	Size   int64      `json:"size"`
+	Reason string     `json:"reason"`
+}
+
+// SyncPlan is the full set of steps needed to mirror a local directory to a prefix, sorted by key
+type SyncPlan struct {
+	Items []SyncItem `json:"items"`
+}
+
+type remoteObject struct {
+	size         int64
+	etag         string
+	lastModified time.Time
+	// opaqueETag is set when the ETag is not the MD5 of the content even for a single-part upload,
+	// as with SSE-KMS and SSE-C encryption
+	opaqueETag bool
+}
+
+// PlanSync compares localDir with the objects under prefix and works out which files need
+// uploading, which remote keys would be deleted (only when deleteExtra is set) and which are
+// already up to date. Only files selected by filter take part; remote keys it rules out are never
+// deleted. It does not modify the bucket.
+func (c *Client) PlanSync(ctx context.Context, localDir, prefix string, deleteExtra bool, filter KeyFilter) (*SyncPlan, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	prefix = strings.Trim(prefix, "/")
+
+	remote, err := c.listRemote(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &SyncPlan{}
+	seen := make(map[string]bool)
+	err = filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() && rel != "." && filter.Excluded(rel) {
+			c.log().Debug("skipped excluded directory", "path", p)
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if !filter.Match(rel) {
+			c.log().Debug("skipped filtered file", "path", p)
+			return nil
+		}
+		key := path.Join(prefix, rel)
+		seen[key] = true
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		item := SyncItem{Key: key, Path: p, Size: info.Size()}
+		obj := remote[key]
+		item.Action, item.Reason, err = compareLocal(p, info, obj)
+		if err != nil {
+			return err
+		}
+		if item.Action == SyncUpload && item.Reason == "etag differs" {
+			// Listings don't say how an object is encrypted, so only check before re-uploading
+			head, err := c.S3.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &c.Bucket, Key: &key})
+			if err != nil {
+				return fmt.Errorf("checking %s: %w", key, err)
+			}
+			if obj.opaqueETag = !etagIsMD5(head); obj.opaqueETag {
+				if item.Action, item.Reason, err = compareLocal(p, info, obj); err != nil {
+					return err
+				}
+			}
+		}
+		plan.Items = append(plan.Items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", localDir, err)
+	}
+
+	if deleteExtra {
+		for key, obj := range remote {
+			if !seen[key] && filter.Match(strings.TrimPrefix(key, prefix+"/")) {
+				plan.Items = append(plan.Items, SyncItem{Action: SyncDelete, Key: key, Size: obj.size, Reason: "not present locally"})
+			}
+		}
+	}
+
+	sort.Slice(plan.Items, func(i, j int) bool { return plan.Items[i].Key < plan.Items[j].Key })
+	return plan, nil
+}
+
+// SyncReport counts what Sync did
+type SyncReport struct {
+	Uploaded int `json:"uploaded"`
+	Skipped  int `json:"skipped"`
+	Deleted  int `json:"deleted"`
+	// Failed counts uploads and deletions that returned an error
+	Failed int `json:"failed"`
+}
+
+// Sync mirrors localDir into prefix: new and changed files are uploaded, unchanged ones skipped and,
+// when deleteExtra is set, remote keys that no longer exist locally are deleted. A file counts as
+// changed when its size differs or, for objects uploaded in one part and not encrypted with SSE-KMS or
+// SSE-C, its MD5 differs from the ETag; other objects count as changed when the file is newer.
+// Failures don't stop the sync; the report counts them and the error combines them. Use PlanSync to
+// see what would happen without changing anything; with the client's DryRun set, Sync prints that
+// plan and the report counts what would be done. filter selects the files as in PlanSync, and opts
+// applies to every upload except for Key, since each file keeps its own key. Gzip is refused: the
+// compressed objects would never match their local files and be uploaded again on every sync.
+func (c *Client) Sync(ctx context.Context, localDir, prefix string, deleteExtra bool, filter KeyFilter, opts UploadOptions) (SyncReport, error) {
+	var report SyncReport
+	if opts.Gzip {
+		return report, fmt.Errorf("gzip can't be used with sync: compressed objects never match their local files")
+	}
+	// The plan has already compared every file with its object
+	opts.Key, opts.SkipIfSame = "", false
+	plan, err := c.PlanSync(ctx, localDir, prefix, deleteExtra, filter)
+	if err != nil {
+		return report, err
+	}
+	if c.DryRun {
+		for _, item := range plan.Items {
+			switch item.Action {
+			case SyncUpload:
+				report.Uploaded++
+			case SyncDelete:
+				report.Deleted++
+			case SyncSkip:
+				report.Skipped++
+			}
+		}
+		return report, plan.Write(os.Stdout, "text")
+	}
+
+	var (
+		deletes []string
+		errs    []error
+	)
+	for _, item := range plan.Items {
+		switch item.Action {
+		case SyncUpload:
+			dir := path.Dir(item.Key)
+			if dir == "." {
+				dir = ""
+			}
+			if _, err := c.UploadFile(ctx, item.Path, dir, true, opts); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", item.Key, err))
+				report.Failed++
+				continue
+			}
+			fmt.Printf("Uploaded: %s (%s)\n", item.Key, item.Reason)
+			report.Uploaded++
+		case SyncDelete:
+			deletes = append(deletes, item.Key)
+		case SyncSkip:
+			c.log().Debug("skipped unchanged file", "key", item.Key, "path", item.Path)
+			report.Skipped++
+		}
+	}
+
+	for start := 0; start < len(deletes); start += maxDeleteBatch {
+		end := min(start+maxDeleteBatch, len(deletes))
+		removed, failed, err := c.deleteBatch(ctx, deletes[start:end])
+		for _, key := range removed {
+			fmt.Printf("Deleted: %s\n", key)
+		}
+		report.Deleted += len(removed)
+		report.Failed += len(failed)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// listRemote collects every object under prefix keyed by its full key
+func (c *Client) listRemote(ctx context.Context, prefix string) (map[string]remoteObject, error) {
+	input := &s3.ListObjectsV2Input{Bucket: &c.Bucket}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix + "/")
+	}
+
+	remote := make(map[string]remoteObject)
+	paginator := s3.NewListObjectsV2Paginator(c.S3, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing files: %w", err)
+		}
+		for _, item := range page.Contents {
+			remote[aws.ToString(item.Key)] = remoteObject{
+				size:         aws.ToInt64(item.Size),
+				etag:         strings.Trim(aws.ToString(item.ETag), `"`),
+				lastModified: aws.ToTime(item.LastModified),
+			}
+		}
+	}
+	return remote, nil
+}
+
+// compareLocal decides whether a local file needs uploading given the remote object (zero if missing)
+func compareLocal(p string, info fs.FileInfo, obj remoteObject) (SyncAction, string, error) {
+	switch {
+	case obj.etag == "" && obj.lastModified.IsZero():
+		return SyncUpload, "new file", nil
+	case obj.size != info.Size():
+		return SyncUpload, "size differs", nil
+	case !strings.Contains(obj.etag, "-") && !obj.opaqueETag:
+		// Single-part uploads carry the MD5 of the content as their ETag
+		sum, err := fileMD5(p)
+		if err != nil {
+			return "", "", err
+		}
+		if sum != obj.etag {
+			return SyncUpload, "etag differs", nil
+		}
+		return SyncSkip, "unchanged", nil
+	case info.ModTime().After(obj.lastModified):
+		return SyncUpload, "mtime newer", nil
+	default:
+		return SyncSkip, "unchanged", nil
+	}
+}
+
+// etagIsMD5 reports whether the ETag of a single-part object is the MD5 of its content, which is not
+// the case for objects encrypted with SSE-KMS or a customer-provided key
+func etagIsMD5(head *s3.HeadObjectOutput) bool {
+	switch head.ServerSideEncryption {
+	case types.ServerSideEncryptionAwsKms, types.ServerSideEncryptionAwsKmsDsse:
+		return false
+	}
+	return head.SSECustomerAlgorithm == nil
+}
+
+// fileMD5 returns the hex MD5 digest of the file at p
+func fileMD5(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Counts returns how many items of each action the plan contains
+func (p *SyncPlan) Counts() (uploads, deletes, skips int) {
+	for _, item := range p.Items {
+		switch item.Action {
+		case SyncUpload:
+			uploads++
+		case SyncDelete:
+			deletes++
+		case SyncSkip:
+			skips++
+		}
+	}
+	return uploads, deletes, skips
+}
+
+// Write renders the plan to w as "text" or "json"
+func (p *SyncPlan) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, p)
+	case "text", "":
+		for _, item := range p.Items {
+			fmt.Fprintf(w, "%-6s %s (Size: %d, %s)\n", item.Action, item.Key, item.Size, item.Reason)
+		}
+		uploads, deletes, skips := p.Counts()
+		fmt.Fprintf(w, "Plan: %d to upload, %d to delete, %d unchanged\n", uploads, deletes, skips)
+		return nil
+	default:
+		return unknownFormat(format)
+	}
+}
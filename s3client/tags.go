@@ -0,0 +1,100 @@
+package s3client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// MaxObjectTags is the most tags S3 allows on one object
+const MaxObjectTags = 10
+
+// tagPattern matches the characters S3 allows in tag keys and values
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// GetTags returns the tags of key
+func (c *Client) GetTags(ctx context.Context, key string) (map[string]string, error) {
+	key = strings.TrimPrefix(key, "/")
+	out, err := c.S3.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: &c.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("getting tags: %w", err)
+	}
+
+	tags := make(map[string]string, len(out.TagSet))
+	for _, t := range out.TagSet {
+		tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	return tags, nil
+}
+
+// SetTags replaces all tags of key with tags; an empty map removes them. With DryRun set it only
+// prints the change.
+func (c *Client) SetTags(ctx context.Context, key string, tags map[string]string) error {
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+	key = strings.TrimPrefix(key, "/")
+	if c.DryRun {
+		fmt.Printf("Would tag: %s (%d tags)\n", key, len(tags))
+		return nil
+	}
+
+	tagSet := make([]types.Tag, 0, len(tags))
+	for k, v := range tags {
+		tagSet = append(tagSet, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	_, err := c.S3.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  &c.Bucket,
+		Key:     &key,
+		Tagging: &types.Tagging{TagSet: tagSet},
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return fmt.Errorf("setting tags: %w", err)
+	}
+	return nil
+}
+
+// validateTags checks tags against the limits S3 enforces
+func validateTags(tags map[string]string) error {
+	if len(tags) > MaxObjectTags {
+		return fmt.Errorf("%d tags given but S3 allows at most %d per object", len(tags), MaxObjectTags)
+	}
+	for k, v := range tags {
+		switch {
+		case k == "":
+			return fmt.Errorf("tag keys must not be empty")
+		case utf8.RuneCountInString(k) > 128:
+			return fmt.Errorf("tag key %q is longer than 128 characters", k)
+		case utf8.RuneCountInString(v) > 256:
+			return fmt.Errorf("tag value for %q is longer than 256 characters", k)
+		case !tagPattern.MatchString(k) || !tagPattern.MatchString(v):
+			return fmt.Errorf("tag %s=%s may only contain letters, digits, spaces and _ . : / = + - @", k, v)
+		}
+	}
+	return nil
+}
+
+// encodeTags renders tags as the URL query string PutObject expects in x-amz-tagging
+func encodeTags(tags map[string]string) string {
+	values := url.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
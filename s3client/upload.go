@@ -0,0 +1,573 @@
+package s3client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// MiB is one mebibyte, the unit part sizes are configured in
+const MiB = 1024 * 1024
+
+// StreamPartSize is the multipart part size used for uploads of unknown length such as stdin.
+// With S3's limit of 10,000 parts this allows streams of up to about 156 GiB.
+const StreamPartSize = 16 * MiB
+
+// UploadOptions holds optional settings applied to uploaded objects
+type UploadOptions struct {
+	// Key is the full object key to upload to. When set, UploadFile ignores the file name and directory;
+	// UploadFiles ignores it since every file needs its own key.
+	Key string
+	// ContentType overrides the MIME type detected from the file extension or content
+	ContentType string
+	// ACL is a canned ACL such as "public-read"; empty keeps the bucket default
+	ACL string
+	// CacheControl is served as the Cache-Control header, e.g. "public, max-age=31536000"
+	CacheControl string
+	// ContentDisposition is served as the Content-Disposition header, e.g. `attachment; filename="report.pdf"`
+	ContentDisposition string
+	// StorageClass such as "STANDARD_IA" or "GLACIER"; empty keeps the bucket default (usually STANDARD)
+	StorageClass string
+	// SSE selects server-side encryption: "AES256" or "aws:kms"; empty keeps the bucket default
+	SSE string
+	// SSEKMSKeyID is the KMS key used when SSE is "aws:kms"; empty uses the bucket's default KMS key
+	SSEKMSKeyID string
+	// Metadata is stored as x-amz-meta-* user metadata. S3 lowercases the names, so keys that only
+	// differ in case overwrite each other.
+	Metadata map[string]string
+	// Tags are stored as object tags, e.g. for lifecycle rules. S3 allows at most MaxObjectTags.
+	Tags map[string]string
+	// Verify sends a SHA-256 checksum with the upload and afterwards checks the stored object against it
+	Verify bool
+	// SkipIfSame makes UploadFile leave an existing object alone when it already has the file's content:
+	// same MD5 for single-part objects, otherwise same size and not older than the file
+	SkipIfSame bool
+	// Gzip compresses the data before uploading and stores it with "Content-Encoding: gzip", appending
+	// ".gz" to the key unless GzipKeepKey is set. Data that is already compressed is uploaded as is.
+	Gzip        bool
+	GzipKeepKey bool
+
+	// contentEncoding is set by Gzip once the data has actually been compressed
+	contentEncoding string
+}
+
+// withDefaults returns o with every empty field taken from d
+func (o UploadOptions) withDefaults(d UploadOptions) UploadOptions {
+	if o.ContentType == "" {
+		o.ContentType = d.ContentType
+	}
+	if o.ACL == "" {
+		o.ACL = d.ACL
+	}
+	if o.CacheControl == "" {
+		o.CacheControl = d.CacheControl
+	}
+	if o.SSE == "" {
+		o.SSE = d.SSE
+		if o.SSEKMSKeyID == "" {
+			o.SSEKMSKeyID = d.SSEKMSKeyID
+		}
+	}
+	return o
+}
+
+// MetadataCollisions returns the groups of metadata keys that S3 would treat as the same name
+// once lowercased, e.g. "Owner" and "owner"
+func MetadataCollisions(metadata map[string]string) [][]string {
+	byLower := make(map[string][]string)
+	for k := range metadata {
+		lower := strings.ToLower(k)
+		byLower[lower] = append(byLower[lower], k)
+	}
+	var collisions [][]string
+	for _, keys := range byLower {
+		if len(keys) > 1 {
+			sort.Strings(keys)
+			collisions = append(collisions, keys)
+		}
+	}
+	return collisions
+}
+
+// validate checks the options for combinations S3 would reject
+func (o UploadOptions) validate() error {
+	if strings.HasPrefix(o.Key, "/") {
+		return fmt.Errorf("key %q must not start with a slash", o.Key)
+	}
+	for k, v := range o.Metadata {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("metadata keys must not be empty")
+		}
+		// Metadata travels in x-amz-meta-* headers, so names must be valid header names
+		if i := strings.IndexFunc(k, func(r rune) bool { return !isHeaderTokenRune(r) }); i >= 0 {
+			r, _ := utf8.DecodeRuneInString(k[i:])
+			return fmt.Errorf("metadata key %q contains %q, which is not allowed in a header name", k, r)
+		}
+		if i := strings.IndexFunc(v, func(r rune) bool { return r < 0x20 || r > 0x7e }); i >= 0 {
+			return fmt.Errorf("metadata value for %q must be printable ASCII", k)
+		}
+	}
+	if err := validateTags(o.Tags); err != nil {
+		return err
+	}
+	if err := checkEnum("ACL", o.ACL, types.ObjectCannedACL("").Values()); err != nil {
+		return err
+	}
+	if err := checkEnum("storage class", o.StorageClass, types.StorageClass("").Values()); err != nil {
+		return err
+	}
+	switch types.ServerSideEncryption(o.SSE) {
+	case "", types.ServerSideEncryptionAes256:
+		if o.SSEKMSKeyID != "" {
+			return fmt.Errorf("a KMS key id requires sse = %q", types.ServerSideEncryptionAwsKms)
+		}
+	case types.ServerSideEncryptionAwsKms:
+	default:
+		return fmt.Errorf("unsupported sse %q (want %s or %s)", o.SSE, types.ServerSideEncryptionAes256, types.ServerSideEncryptionAwsKms)
+	}
+	return nil
+}
+
+// checkEnum returns an error listing the valid values when value is set but not one of them
+func checkEnum[T ~string](name, value string, valid []T) error {
+	if value == "" || slices.Contains(valid, T(value)) {
+		return nil
+	}
+	names := make([]string, len(valid))
+	for i, v := range valid {
+		names[i] = string(v)
+	}
+	return fmt.Errorf("unknown %s %q (want one of %s)", name, value, strings.Join(names, ", "))
+}
+
+// UploadFile uploads a file with overwrite confirmation
+func (c *Client) UploadFile(ctx context.Context, filePath, directory string, overwrite bool, opts UploadOptions) (string, error) {
+	_, url, err := c.uploadFile(ctx, filePath, directory, overwrite, opts)
+	return url, err
+}
+
+// uploadFile is UploadFile, also returning the key the file was stored under, which includes the
+// ".gz" Gzip may append. The key is empty when the upload failed before it was known.
+func (c *Client) uploadFile(ctx context.Context, filePath, directory string, overwrite bool, opts UploadOptions) (key, url string, err error) {
+	opts = opts.withDefaults(c.Defaults)
+	if err := opts.validate(); err != nil {
+		return "", "", err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", "", fmt.Errorf("opening file: %w", err)
+	}
+	defer file.Close()
+
+	if opts.ContentType == "" {
+		opts.ContentType, err = detectContentType(file)
+		if err != nil {
+			return "", "", fmt.Errorf("detecting content type: %w", err)
+		}
+	}
+
+	key = opts.Key
+	if key == "" {
+		key = objectKey(filePath, directory)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return key, "", fmt.Errorf("opening file: %w", err)
+	}
+	if opts.Gzip && !isCompressed(filePath, opts.ContentType) {
+		if !opts.GzipKeepKey {
+			key += ".gz"
+		}
+		// A dry run only reports the key, so there is no need to compress
+		if !c.DryRun {
+			compressed, size, err := gzipToTemp(file)
+			if err != nil {
+				return key, "", err
+			}
+			defer os.Remove(compressed.Name())
+			defer compressed.Close()
+			c.log().Debug("compressed", "path", filePath, "size", info.Size(), "compressedSize", size)
+			file, filePath = compressed, compressed.Name()
+			if info, err = file.Stat(); err != nil {
+				return key, "", fmt.Errorf("compressing: %w", err)
+			}
+			opts.contentEncoding = "gzip"
+		}
+	}
+	if opts.SkipIfSame {
+		same, err := c.sameAsRemote(ctx, filePath, info, key)
+		if err != nil {
+			return key, "", err
+		}
+		if same {
+			c.log().Info("skipped unchanged file", "key", key, "path", filePath)
+			return key, c.objectURL(key), nil
+		}
+	}
+	url, err = c.upload(ctx, file, info.Size(), key, overwrite, opts)
+	return key, url, err
+}
+
+// UploadReader uploads everything read from r to key. The size of r doesn't need to be known in
+// advance: the data is sent as a multipart upload in parts of StreamPartSize, which bounds memory use
+// to roughly StreamPartSize times the upload concurrency.
+func (c *Client) UploadReader(ctx context.Context, r io.Reader, key string, overwrite bool, opts UploadOptions) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("an object key is required")
+	}
+	opts.Key = key
+	opts = opts.withDefaults(c.Defaults)
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+
+	if opts.ContentType == "" {
+		// Peek rather than read so the sniffed bytes are still part of the upload
+		br := bufio.NewReaderSize(r, 512)
+		head, err := br.Peek(512)
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("reading input: %w", err)
+		}
+		opts.ContentType = http.DetectContentType(head)
+		r = br
+	}
+	if opts.Gzip && !isCompressed(key, opts.ContentType) {
+		if !opts.GzipKeepKey {
+			key += ".gz"
+		}
+		compressed := gzipStream(r)
+		defer compressed.Close()
+		r = compressed
+		opts.contentEncoding = "gzip"
+	}
+
+	return c.upload(ctx, r, 0, key, overwrite, opts)
+}
+
+// validateUploadTuning rejects part sizes S3 would refuse
+func (c *Client) validateUploadTuning() error {
+	if c.PartSize != 0 && c.PartSize < manager.MinUploadPartSize {
+		return fmt.Errorf("part size %s is below the S3 minimum of 5 MiB", formatBytes(c.PartSize))
+	}
+	if c.UploadConcurrency < 0 {
+		return fmt.Errorf("upload concurrency must not be negative")
+	}
+	return nil
+}
+
+// upload sends body to key after checking whether it would overwrite an existing object.
+// size is only used for progress reporting and may be 0 when unknown.
+func (c *Client) upload(ctx context.Context, body io.Reader, size int64, key string, overwrite bool, opts UploadOptions) (string, error) {
+	if err := c.validateUploadTuning(); err != nil {
+		return "", err
+	}
+
+	// Check existence
+	_, err := c.S3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &c.Bucket,
+		Key:    &key,
+	})
+	if c.DryRun {
+		note := ""
+		if err == nil {
+			note = ", replaces the existing object"
+		}
+		if size > 0 {
+			fmt.Printf("Would upload: %s (Size: %d%s)\n", key, size, note)
+		} else {
+			fmt.Printf("Would upload: %s (Size: unknown%s)\n", key, note)
+		}
+		return c.objectURL(key), nil
+	}
+	if err == nil && !overwrite {
+		if !c.confirm(fmt.Sprintf("File %s already exists. Overwrite?", key)) {
+			return "", fmt.Errorf("upload cancelled by user")
+		}
+	}
+
+	var digest *uploadDigest
+	if opts.Verify {
+		_, seekable := body.(io.Seeker)
+		body, digest, err = digestBody(body, c.partSize(seekable, size))
+		if err != nil {
+			return "", fmt.Errorf("computing checksum: %w", err)
+		}
+	}
+
+	body = c.withRateLimit(ctx, body)
+	var prog *progress
+	if c.Progress != nil {
+		prog = newProgress(c.Progress, key, size)
+		body = withProgress(body, prog)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      &c.Bucket,
+		Key:         &key,
+		Body:        body,
+		ContentType: aws.String(opts.ContentType),
+	}
+	if opts.ACL != "" {
+		input.ACL = types.ObjectCannedACL(opts.ACL)
+	}
+	if opts.StorageClass != "" {
+		input.StorageClass = types.StorageClass(opts.StorageClass)
+	}
+	if opts.SSE != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(opts.SSE)
+	}
+	if opts.SSEKMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(opts.SSEKMSKeyID)
+	}
+	if opts.contentEncoding != "" {
+		input.ContentEncoding = aws.String(opts.contentEncoding)
+	}
+	if opts.CacheControl != "" {
+		input.CacheControl = aws.String(opts.CacheControl)
+	}
+	if opts.ContentDisposition != "" {
+		input.ContentDisposition = aws.String(opts.ContentDisposition)
+	}
+	if opts.CacheControl != "" || opts.ContentDisposition != "" {
+		c.log().Info("setting headers", "key", key, "cacheControl", opts.CacheControl, "contentDisposition", opts.ContentDisposition)
+	}
+	if len(opts.Metadata) > 0 {
+		input.Metadata = opts.Metadata
+	}
+	if len(opts.Tags) > 0 {
+		input.Tagging = aws.String(encodeTags(opts.Tags))
+	}
+	if opts.Verify {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmSha256
+	}
+
+	_, seekable := body.(io.Seeker)
+	out, err := c.newUploader(seekable, size).Upload(ctx, input)
+	if prog != nil {
+		prog.done()
+	}
+	if err != nil {
+		// The uploader aborts a failed multipart upload itself, but with the request context; once that
+		// is cancelled the abort fails too and the parts stay billed until a lifecycle rule removes them
+		var multipartErr manager.MultiUploadFailure
+		if errors.As(err, &multipartErr) && ctx.Err() != nil {
+			c.abortUpload(context.WithoutCancel(ctx), key, multipartErr.UploadID())
+		}
+		var apiErr smithy.APIError
+		if opts.ACL != "" && errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessControlListNotSupported" {
+			return "", fmt.Errorf("uploading file: bucket %s does not accept ACLs (object ownership is set to BucketOwnerEnforced); "+
+				"upload without an ACL and grant access with a bucket policy instead: %w", c.Bucket, err)
+		}
+		return "", fmt.Errorf("uploading file: %w", err)
+	}
+
+	// Report what S3 says it applied rather than what was requested
+	c.log().Info("uploaded", "key", key, "sse", string(out.ServerSideEncryption), "kmsKeyId", aws.ToString(out.SSEKMSKeyId))
+
+	if digest != nil {
+		if err := c.verifyUpload(ctx, key, digest); err != nil {
+			return "", err
+		}
+	}
+
+	return c.objectURL(key), nil
+}
+
+// newUploader returns an uploader for a body of size bytes (0 when unknown) that uses the client's
+// part size and upload concurrency
+func (c *Client) newUploader(seekable bool, size int64) *manager.Uploader {
+	return manager.NewUploader(c.S3, func(u *manager.Uploader) {
+		u.PartSize = c.partSize(seekable, size)
+		if c.UploadConcurrency > 0 {
+			u.Concurrency = c.UploadConcurrency
+		}
+	})
+}
+
+// partSize returns the part size to upload a body of size bytes (0 when unknown) with: PartSize or
+// the SDK default, StreamPartSize for bodies that can't seek, and larger when that would need more
+// than manager.MaxUploadParts parts, like the uploader itself adjusts it for seekable bodies
+func (c *Client) partSize(seekable bool, size int64) int64 {
+	partSize := manager.DefaultUploadPartSize
+	if !seekable {
+		partSize = StreamPartSize
+	}
+	if c.PartSize > 0 {
+		partSize = c.PartSize
+	}
+	if size > 0 && size/partSize >= int64(manager.MaxUploadParts) {
+		partSize = size/int64(manager.MaxUploadParts) + 1
+	}
+	return partSize
+}
+
+// objectURL returns the public URL of key under ReturnURL
+func (c *Client) objectURL(key string) string {
+	return fmt.Sprintf("%s/%s", strings.TrimRight(c.ReturnURL, "/"), strings.TrimLeft(key, "/"))
+}
+
+// sameAsRemote reports whether key already holds the content of the local file, using the same
+// comparison as PlanSync
+func (c *Client) sameAsRemote(ctx context.Context, filePath string, info os.FileInfo, key string) (bool, error) {
+	head, err := c.S3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &c.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("checking existing object: %w", err)
+	}
+	action, _, err := compareLocal(filePath, info, remoteObject{
+		size:         aws.ToInt64(head.ContentLength),
+		etag:         strings.Trim(aws.ToString(head.ETag), `"`),
+		lastModified: aws.ToTime(head.LastModified),
+		opaqueETag:   !etagIsMD5(head),
+	})
+	if err != nil {
+		return false, err
+	}
+	return action == SyncSkip, nil
+}
+
+// abortUploadTimeout bounds the cleanup of an interrupted multipart upload
+const abortUploadTimeout = 10 * time.Second
+
+// abortUpload aborts the multipart upload uploadID of key so its parts are deleted. Failures are only
+// logged since the upload has already failed.
+func (c *Client) abortUpload(ctx context.Context, key, uploadID string) {
+	ctx, cancel := context.WithTimeout(ctx, abortUploadTimeout)
+	defer cancel()
+	if err := c.AbortMultipartUpload(ctx, key, uploadID); err != nil {
+		c.log().Warn("could not abort interrupted multipart upload; its parts are left in the bucket",
+			"key", key, "uploadId", uploadID, "error", err)
+		return
+	}
+	c.log().Info("aborted interrupted multipart upload", "key", key, "uploadId", uploadID)
+}
+
+// UploadResult describes the outcome of uploading a single file as part of a batch
+type UploadResult struct {
+	Path string
+	// Key is the key the file was stored under, including any ".gz" added by Gzip
+	Key string
+	URL string
+	Err error
+}
+
+// UploadFiles uploads several files concurrently using a bounded pool of workers.
+// A failure on one file is recorded in its UploadResult and does not abort the batch;
+// cancelling ctx stops scheduling new uploads and marks the remaining ones with the context error.
+func (c *Client) UploadFiles(ctx context.Context, paths []string, keyPrefix string, concurrency int, overwrite bool, opts UploadOptions) ([]UploadResult, error) {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	opts.Key = ""
+
+	results := make([]UploadResult, len(paths))
+	for i, p := range paths {
+		results[i] = UploadResult{Path: p, Key: objectKey(p, keyPrefix)}
+	}
+
+	// Every worker shares a child of ctx, so cancelling the caller's context (or returning early)
+	// stops all in-flight uploads at once
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					results[i].Err = err
+					continue
+				}
+				key, url, err := c.uploadFile(ctx, paths[i], keyPrefix, overwrite, opts)
+				if key != "" {
+					results[i].Key = key
+				}
+				results[i].URL, results[i].Err = url, err
+			}
+		}()
+	}
+
+	scheduled := 0
+schedule:
+	for scheduled < len(paths) {
+		select {
+		case jobs <- scheduled:
+			scheduled++
+		case <-ctx.Done():
+			break schedule
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := scheduled; i < len(paths); i++ {
+		results[i].Err = ctx.Err()
+	}
+	return results, ctx.Err()
+}
+
+// isHeaderTokenRune reports whether r may appear in an HTTP header name
+func isHeaderTokenRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	default:
+		return r < 0x80 && strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+	}
+}
+
+// detectContentType guesses the MIME type of file from its extension, falling back to sniffing
+// the first 512 bytes. The file offset is restored to the start afterwards so the upload sees every byte.
+func detectContentType(file *os.File) (string, error) {
+	if ct := mime.TypeByExtension(filepath.Ext(file.Name())); ct != "" {
+		return ct, nil
+	}
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// objectKey derives the object key for a local file placed under directory
+func objectKey(filePath, directory string) string {
+	key := filepath.Base(filePath)
+	if directory != "" {
+		dir := strings.Trim(directory, "/")
+		key = filepath.Join(dir, key)
+	}
+	return filepath.ToSlash(key)
+}
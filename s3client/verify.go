@@ -0,0 +1,142 @@
+package s3client
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// uploadDigest holds the local digests an upload is verified against. MD5 is only needed for
+// endpoints that don't return checksums, where the ETag of a single-part upload is the MD5; parts is
+// only needed for multipart uploads.
+type uploadDigest struct {
+	sha256 hash.Hash
+	md5    hash.Hash
+	parts  *partDigest
+}
+
+func newUploadDigest(partSize int64) *uploadDigest {
+	return &uploadDigest{sha256: sha256.New(), md5: md5.New(), parts: &partDigest{size: partSize, cur: sha256.New()}}
+}
+
+func (d *uploadDigest) Write(p []byte) (int, error) {
+	d.sha256.Write(p)
+	d.parts.Write(p)
+	return d.md5.Write(p)
+}
+
+// partDigest computes the SHA-256 of every part of a multipart upload, splitting the data at size
+// bytes the same way the uploader does
+type partDigest struct {
+	size    int64
+	written int64 // bytes of the current part
+	cur     hash.Hash
+	sums    [][]byte
+}
+
+func (p *partDigest) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		chunk := min(int64(len(b)), p.size-p.written)
+		p.cur.Write(b[:chunk])
+		p.written += chunk
+		b = b[chunk:]
+		if p.written == p.size {
+			p.sums = append(p.sums, p.cur.Sum(nil))
+			p.cur.Reset()
+			p.written = 0
+		}
+	}
+	return n, nil
+}
+
+// composite returns the checksum S3 reports for a multipart upload, "<base64>-<parts>": the SHA-256
+// of the concatenated part digests, followed by the number of parts
+func (p *partDigest) composite() string {
+	sums := p.sums[:len(p.sums):len(p.sums)]
+	if p.written > 0 {
+		sums = append(sums, p.cur.Sum(nil))
+	}
+	h := sha256.New()
+	for _, sum := range sums {
+		h.Write(sum)
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)) + "-" + strconv.Itoa(len(sums))
+}
+
+// digestBody prepares body for a verified upload sent in parts of partSize. Seekable bodies are hashed up front and rewound, so
+// the uploader can still seek and retry; streams are hashed as the uploader reads them. The digest is
+// only complete once the upload has consumed body.
+func digestBody(body io.Reader, partSize int64) (io.Reader, *uploadDigest, error) {
+	d := newUploadDigest(partSize)
+	rs, ok := body.(io.ReadSeeker)
+	if !ok {
+		return io.TeeReader(body, d), d, nil
+	}
+
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := io.Copy(d, rs); err != nil {
+		return nil, nil, err
+	}
+	if _, err := rs.Seek(start, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	return body, d, nil
+}
+
+// verifyUpload compares the checksum S3 stored for key with the local digest. When the endpoint
+// returns no checksum it falls back to comparing the ETag with the MD5, which only works for
+// single-part uploads.
+func (c *Client) verifyUpload(ctx context.Context, key string, d *uploadDigest) error {
+	head, err := c.S3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       &c.Bucket,
+		Key:          &key,
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return fmt.Errorf("verifying upload: %w", err)
+	}
+
+	if remote := aws.ToString(head.ChecksumSHA256); remote != "" {
+		// A multipart upload gets a checksum of its part checksums ("<base64>-<parts>")
+		if _, remoteParts, multipart := strings.Cut(remote, "-"); multipart {
+			local := d.parts.composite()
+			if _, localParts, _ := strings.Cut(local, "-"); localParts != remoteParts {
+				// Split differently than expected, so only the server's check of every part applies
+				c.log().Info("only verified the parts of the upload", "key", key, "parts", remoteParts, "expectedParts", localParts)
+				return nil
+			}
+			if remote != local {
+				return fmt.Errorf("verifying upload: SHA-256 of the parts of %s is %s in the bucket but %s locally", key, remote, local)
+			}
+			return nil
+		}
+		if local := base64.StdEncoding.EncodeToString(d.sha256.Sum(nil)); remote != local {
+			return fmt.Errorf("verifying upload: SHA-256 of %s is %s in the bucket but %s locally", key, remote, local)
+		}
+		return nil
+	}
+
+	etag := strings.Trim(aws.ToString(head.ETag), `"`)
+	if strings.Contains(etag, "-") {
+		return fmt.Errorf("verifying upload: the endpoint returned no checksum for %s and the ETag of a multipart upload is not an MD5", key)
+	}
+	if local := hex.EncodeToString(d.md5.Sum(nil)); etag != local {
+		return fmt.Errorf("verifying upload: ETag of %s is %s in the bucket but the local MD5 is %s", key, etag, local)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ObjectVersion describes one version of an object in a versioned bucket
+type ObjectVersion struct {
+	Key          string    `json:"key"`
+	VersionID    string    `json:"versionId"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	// IsLatest marks the current version, the one a plain GET returns
+	IsLatest bool `json:"isLatest"`
+	// DeleteMarker marks the placeholder a plain delete leaves in a versioned bucket; it has no data
+	DeleteMarker bool `json:"deleteMarker"`
+}
+
+// ListVersions returns every version and delete marker of the keys starting with prefix, grouped by key
+// with the newest version first
+func (c *Client) ListVersions(ctx context.Context, prefix string) ([]ObjectVersion, error) {
+	input := &s3.ListObjectVersionsInput{Bucket: &c.Bucket}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	versions := []ObjectVersion{}
+	paginator := s3.NewListObjectVersionsPaginator(c.S3, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing versions: %w", err)
+		}
+		// S3 returns versions and delete markers in separate lists; merge them back into key order
+		var pageVersions []ObjectVersion
+		for _, v := range page.Versions {
+			pageVersions = append(pageVersions, ObjectVersion{
+				Key:          aws.ToString(v.Key),
+				VersionID:    aws.ToString(v.VersionId),
+				Size:         aws.ToInt64(v.Size),
+				LastModified: aws.ToTime(v.LastModified),
+				IsLatest:     aws.ToBool(v.IsLatest),
+			})
+		}
+		for _, m := range page.DeleteMarkers {
+			pageVersions = append(pageVersions, ObjectVersion{
+				Key:          aws.ToString(m.Key),
+				VersionID:    aws.ToString(m.VersionId),
+				LastModified: aws.ToTime(m.LastModified),
+				IsLatest:     aws.ToBool(m.IsLatest),
+				DeleteMarker: true,
+			})
+		}
+		sortVersions(pageVersions)
+		versions = append(versions, pageVersions...)
+	}
+	return versions, nil
+}
+
+// sortVersions orders versions by key, newest first within a key
+func sortVersions(versions []ObjectVersion) {
+	slices.SortStableFunc(versions, func(a, b ObjectVersion) int {
+		if c := strings.Compare(a.Key, b.Key); c != 0 {
+			return c
+		}
+		return b.LastModified.Compare(a.LastModified)
+	})
+}
+
+// DeleteVersion permanently deletes one version (or delete marker) of key. Unlike a plain delete in
+// a versioned bucket this frees the space the version used.
+func (c *Client) DeleteVersion(ctx context.Context, key, versionID string) error {
+	key = strings.TrimPrefix(key, "/")
+	if versionID == "" {
+		return fmt.Errorf("a version id is required")
+	}
+	if c.DryRun {
+		fmt.Printf("Would delete: %s (Version: %s)\n", key, versionID)
+		return nil
+	}
+	_, err := c.S3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:    &c.Bucket,
+		Key:       &key,
+		VersionId: &versionID,
+	})
+	if err != nil {
+		return fmt.Errorf("deleting version %s of %s: %w", versionID, key, err)
+	}
+	fmt.Printf("Deleted: %s (Version: %s)\n", key, versionID)
+	return nil
+}
+
+// DeleteAllVersions permanently deletes every version and delete marker of key after asking for
+// confirmation, which removes every trace of it from a versioned bucket. It returns how many versions
+// were deleted, or with DryRun set, how many would be.
+func (c *Client) DeleteAllVersions(ctx context.Context, key string) (int, error) {
+	key = strings.TrimPrefix(key, "/")
+	listed, err := c.ListVersions(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	// The listing is by prefix, so it may include longer keys
+	var ids []types.ObjectIdentifier
+	for _, v := range listed {
+		if v.Key != key {
+			continue
+		}
+		if c.DryRun {
+			fmt.Printf("Would delete: %s (Version: %s)\n", key, v.VersionID)
+		}
+		ids = append(ids, types.ObjectIdentifier{Key: aws.String(key), VersionId: aws.String(v.VersionID)})
+	}
+	if c.DryRun || len(ids) == 0 {
+		return len(ids), nil
+	}
+	if !c.confirm(fmt.Sprintf("Permanently delete %d version(s) of %s?", len(ids), key)) {
+		return 0, fmt.Errorf("delete cancelled by user")
+	}
+
+	deleted := 0
+	var errs []error
+	for start := 0; start < len(ids); start += maxDeleteBatch {
+		end := min(start+maxDeleteBatch, len(ids))
+		out, err := c.S3.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: &c.Bucket,
+			Delete: &types.Delete{Objects: ids[start:end]},
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deleting versions: %w", err))
+			continue
+		}
+		for _, d := range out.Deleted {
+			fmt.Printf("Deleted: %s (Version: %s)\n", aws.ToString(d.Key), aws.ToString(d.VersionId))
+		}
+		deleted += len(out.Deleted)
+		for _, e := range out.Errors {
+			errs = append(errs, fmt.Errorf("deleting version %s of %s: %s: %s",
+				aws.ToString(e.VersionId), aws.ToString(e.Key), aws.ToString(e.Code), aws.ToString(e.Message)))
+		}
+	}
+	return deleted, errors.Join(errs...)
+}
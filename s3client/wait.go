@@ -0,0 +1,62 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// ErrWaitTimeout is returned by WaitForObject when the object did not appear in time, and by DeleteFile
+// when it did not disappear in time
+var ErrWaitTimeout = errors.New("timed out waiting for object")
+
+// WaitForObject polls HeadObject until key exists or timeout elapses and returns how long it waited.
+// minDelay and maxDelay bound the backoff between polls; zero keeps the SDK defaults (5s and 120s).
+func (c *Client) WaitForObject(ctx context.Context, key string, timeout, minDelay, maxDelay time.Duration) (time.Duration, error) {
+	if timeout <= 0 {
+		return 0, fmt.Errorf("wait timeout must be greater than zero")
+	}
+	if minDelay > 0 && maxDelay > 0 && minDelay > maxDelay {
+		return 0, fmt.Errorf("minimum delay %s is larger than maximum delay %s", minDelay, maxDelay)
+	}
+
+	key = strings.TrimPrefix(key, "/")
+	waiter := s3.NewObjectExistsWaiter(c.S3, func(o *s3.ObjectExistsWaiterOptions) {
+		if minDelay > 0 {
+			o.MinDelay = minDelay
+		}
+		if maxDelay > 0 {
+			o.MaxDelay = maxDelay
+		}
+	})
+
+	start := time.Now()
+	err := waiter.Wait(ctx, &s3.HeadObjectInput{
+		Bucket: &c.Bucket,
+		Key:    &key,
+	}, timeout)
+	waited := time.Since(start)
+	if err != nil {
+		if waiterTimedOut(ctx, err) {
+			return waited, fmt.Errorf("%w %s after %s", ErrWaitTimeout, key, timeout)
+		}
+		return waited, fmt.Errorf("waiting for %s: %w", key, err)
+	}
+	return waited, nil
+}
+
+// waiterTimedOut reports whether err from an SDK waiter means it ran out of time. The waiter gives up
+// as soon as the time left is shorter than its next delay, so it usually fails before the full wait has
+// passed; any failure that isn't the caller's context ending or a failed request is that.
+func waiterTimedOut(ctx context.Context, err error) bool {
+	var (
+		opErr  *smithy.OperationError
+		apiErr smithy.APIError
+	)
+	return ctx.Err() == nil && !errors.As(err, &opErr) && !errors.As(err, &apiErr)
+}